@@ -60,6 +60,10 @@ function! s:validQuickfixAutoDiagnostics(v)
   return s:validBool(a:v)
 endfunction
 
+function! s:validLocationListDiagnostics(v)
+  return s:validBool(a:v)
+endfunction
+
 function! s:validQuickfixSigns(v)
   return s:validBool(a:v)
 endfunction
@@ -72,6 +76,10 @@ function! s:validHighlightReferences(v)
     return s:validBool(a:v)
 endfunction
 
+function! s:validHighlightSemanticTokens(v)
+    return s:validBool(a:v)
+endfunction
+
 function! s:validHoverDiagnostics(v)
     return s:validBool(a:v)
 endfunction
@@ -104,6 +112,46 @@ function! s:validSymbolStyle(v)
   return [v:true, ""]
 endfunction
 
+function! s:validDiagnosticsVirtualText(v)
+  let valid = ["off", "all", "cursorline"]
+  if index(valid, a:v) < 0
+    return [v:false, "must be one of: ".string(valid)]
+  endif
+  return [v:true, ""]
+endfunction
+
+function! s:validDiagnosticsVirtualTextMaxWidth(v)
+  if type(a:v) != 0 || a:v < 0
+    return [v:false, "must be a non-negative number"]
+  endif
+  return [v:true, ""]
+endfunction
+
+function! s:validDiagnosticsMinSeverity(v)
+  let valid = ["error", "warning", "information", "hint", "none"]
+  if index(valid, a:v) < 0
+    return [v:false, "must be one of: ".string(valid)]
+  endif
+  return [v:true, ""]
+endfunction
+
+function! s:validDiagnosticsMinSeverityBySource(v)
+  if type(a:v) != 4
+    return [v:false, "must be of type dict"]
+  endif
+  for [key, value] in items(a:v)
+    let [ok, err] = s:validDiagnosticsMinSeverity(value)
+    if !ok
+      return [v:false, "value for key ".key." ".err]
+    endif
+  endfor
+  return [v:true, ""]
+endfunction
+
+function! s:validDiagnosticsMinSeverityByPath(v)
+  return s:validDiagnosticsMinSeverityBySource(a:v)
+endfunction
+
 function! s:validStaticcheck(v)
   return s:validBool(a:v)
 endfunction
@@ -156,10 +204,58 @@ function! s:validAnalyses(v)
   return [v:true, ""]
 endfunction
 
+function! s:validInlayHints(v)
+  return s:validAnalyses(a:v)
+endfunction
+
+function! s:validCodeLenses(v)
+  return s:validAnalyses(a:v)
+endfunction
+
+function! s:validShowCodeLenses(v)
+  return s:validBool(a:v)
+endfunction
+
+function! s:validFoldingRanges(v)
+  return s:validBool(a:v)
+endfunction
+
 function! s:openLastProgressWith(v)
   return s:validString(a:v)
 endfunction
 
+function! s:validOpenExternalWith(v)
+  return s:validString(a:v)
+endfunction
+
+function! s:validMultiFileEditStrategy(v)
+  let valid = ["split", "tab", "hidden", "disk"]
+  if index(valid, a:v) < 0
+    return [v:false, "must be one of: ".string(valid)]
+  endif
+  return [v:true, ""]
+endfunction
+
+function! s:validMultiFileEditAutoSave(v)
+  return s:validBool(a:v)
+endfunction
+
+function! s:validMultiFileEditQuickfix(v)
+  return s:validBool(a:v)
+endfunction
+
+function! s:validMultipleLocations(v)
+  let valid = ["popup", "quickfix"]
+  if index(valid, a:v) < 0
+    return [v:false, "must be one of: ".string(valid)]
+  endif
+  return [v:true, ""]
+endfunction
+
+function! s:validMessageRequestTimeout(v)
+  return s:validString(a:v)
+endfunction
+
 function! s:validGofumpt(v)
   return s:validBool(a:v)
 endfunction
@@ -198,6 +294,10 @@ function! s:validExperimentalAllowModfileModifications(v)
   return s:validBool(a:v)
 endfunction
 
+function! s:validExperimentalPullDiagnostics(v)
+  return s:validBool(a:v)
+endfunction
+
 function! s:validExperimentalWorkspaceModule(v)
   return [v:false, "feature has been removed from gopls"]
 endfunction
@@ -214,9 +314,16 @@ let s:validators = {
       \ "SymbolMatcher": function("s:validSymbolMatcher"),
       \ "SymbolStyle": function("s:validSymbolStyle"),
       \ "QuickfixSigns": function("s:validQuickfixSigns"),
+      \ "LocationListDiagnostics": function("s:validLocationListDiagnostics"),
       \ "HighlightDiagnostics": function("s:validHighlightDiagnostics"),
       \ "HighlightReferences": function("s:validHighlightReferences"),
+      \ "HighlightSemanticTokens": function("s:validHighlightSemanticTokens"),
       \ "HoverDiagnostics": function("s:validHoverDiagnostics"),
+      \ "DiagnosticsVirtualText": function("s:validDiagnosticsVirtualText"),
+      \ "DiagnosticsVirtualTextMaxWidth": function("s:validDiagnosticsVirtualTextMaxWidth"),
+      \ "DiagnosticsMinSeverity": function("s:validDiagnosticsMinSeverity"),
+      \ "DiagnosticsMinSeverityBySource": function("s:validDiagnosticsMinSeverityBySource"),
+      \ "DiagnosticsMinSeverityByPath": function("s:validDiagnosticsMinSeverityByPath"),
       \ "Staticcheck": function("s:validStaticcheck"),
       \ "CompleteUnimported": function("s:validCompleteUnimported"),
       \ "GoImportsLocalPrefix": function("s:validGoImportsLocalPrefix"),
@@ -224,7 +331,17 @@ let s:validators = {
       \ "GoplsEnv": function("s:validGoplsEnv"),
       \ "GoplsDirectoryFilters": function("s:validGoplsDirectoryFilters"),
       \ "Analyses": function("s:validAnalyses"),
+      \ "InlayHints": function("s:validInlayHints"),
+      \ "CodeLenses": function("s:validCodeLenses"),
+      \ "ShowCodeLenses": function("s:validShowCodeLenses"),
+      \ "FoldingRanges": function("s:validFoldingRanges"),
       \ "OpenLastProgressWith": function("s:openLastProgressWith"),
+      \ "OpenExternalWith": function("s:validOpenExternalWith"),
+      \ "MessageRequestTimeout": function("s:validMessageRequestTimeout"),
+      \ "MultiFileEditStrategy": function("s:validMultiFileEditStrategy"),
+      \ "MultiFileEditAutoSave": function("s:validMultiFileEditAutoSave"),
+      \ "MultiFileEditQuickfix": function("s:validMultiFileEditQuickfix"),
+      \ "MultipleLocations": function("s:validMultipleLocations"),
       \ "Gofumpt": function("s:validGofumpt"),
       \ "ExperimentalAutoreadLoadedBuffers": function("s:validExperimentalAutoreadLoadedBuffers"),
       \ "ExperimentalMouseTriggeredHoverPopupOptions": function("s:validExperimentalMouseTriggeredHoverPopupOptions"),
@@ -232,6 +349,7 @@ let s:validators = {
       \ "ExperimentalWorkaroundCompleteoptLongest": function("s:validExperimentalWorkaroundCompleteoptLongest"),
       \ "ExperimentalProgressPopups": function("s:validExperimentalProgressPopups"),
       \ "ExperimentalAllowModfileModifications": function("s:validExperimentalProgressPopups"),
+      \ "ExperimentalPullDiagnostics": function("s:validExperimentalPullDiagnostics"),
       \ "ExperimentalWorkspaceModule": function("s:validExperimentalWorkspaceModule"),
       \ "ExperimentalGoplsMemoryMode": function("s:validExperimentalGoplsMemoryMode"),
       \ }
@@ -45,6 +45,10 @@ func (v *vimstate) addBuffer(nb *types.Buffer) error {
 		}
 	}
 
+	// Vim removes text properties when a buffer is unloaded, so any semantic
+	// tokens need to be rendered from scratch.
+	v.resetSemanticTokens(nb.Num)
+
 	if cb, ok := v.buffers[nb.Num]; ok {
 		// reload of buffer, e.v. e!
 		cb.Loaded = nb.Loaded
@@ -60,11 +64,19 @@ func (v *vimstate) addBuffer(nb *types.Buffer) error {
 			if err := v.redefineHighlights(true); err != nil {
 				v.Logf("failed to update highlights for buffer %d: %v", nb.Num, err)
 			}
-			return nil
+			v.updateCodeLenses(cb)
+			v.updateFoldingRanges(cb)
+			return v.updateSemanticTokens(cb)
 		}
 		cb.SetContents(nb.Contents())
 		cb.Version++
-		return v.handleBufferEvent(cb)
+		if err := v.handleBufferEvent(cb); err != nil {
+			return err
+		}
+		v.updateCodeLenses(cb)
+		v.updateFoldingRanges(cb)
+		v.pullBufferDiagnostics(cb)
+		return v.updateSemanticTokens(cb)
 	}
 
 	v.buffers[nb.Num] = nb
@@ -79,7 +91,13 @@ func (v *vimstate) addBuffer(nb *types.Buffer) error {
 		v.Logf("failed to update highlights for buffer %d: %v", nb.Num, err)
 	}
 
-	return v.handleBufferEvent(nb)
+	if err := v.handleBufferEvent(nb); err != nil {
+		return err
+	}
+	v.updateCodeLenses(nb)
+	v.updateFoldingRanges(nb)
+	v.pullBufferDiagnostics(nb)
+	return v.updateSemanticTokens(nb)
 }
 
 func (v *vimstate) bufQuickFixCmdPre(args ...json.RawMessage) error {
@@ -174,7 +192,25 @@ func (v *vimstate) bufChanged(args ...json.RawMessage) (interface{}, error) {
 	if err := v.server.DidChange(context.Background(), params); err != nil {
 		return nil, fmt.Errorf("failed to notify gopls of change: %v", err)
 	}
-	return nil, nil
+	return nil, v.handleBufferChanged(b, changes)
+}
+
+// handleBufferChanged updates the state derived from the contents of b, once
+// gopls has been notified of changes to them. changes are in the form reported
+// by the listener of b, see bufChanged.
+func (v *vimstate) handleBufferChanged(b *types.Buffer, changes []bufChangedChange) error {
+	v.shiftSemanticTokens(b, changes)
+	if err := v.updateSemanticTokens(b); err != nil {
+		return fmt.Errorf("failed to update semantic tokens: %v", err)
+	}
+	if err := v.updateInlayHints(); err != nil {
+		return fmt.Errorf("failed to update inlay hints: %v", err)
+	}
+	v.updateOutline(b)
+	v.updateCodeLenses(b)
+	v.updateFoldingRanges(b)
+	v.pullBufferDiagnostics(b)
+	return nil
 }
 
 func (v *vimstate) bufUnload(args ...json.RawMessage) error {
@@ -183,6 +219,10 @@ func (v *vimstate) bufUnload(args ...json.RawMessage) error {
 		return nil
 	}
 	v.buffers[bufnr].Loaded = false
+	v.resetSemanticTokens(bufnr)
+	v.cancelCodeLensRequest(bufnr)
+	v.resetFoldingRanges(bufnr)
+	v.cancelPullBufferDiagnostics(bufnr)
 	return nil
 }
 
@@ -268,6 +308,12 @@ func (v *vimstate) deleteBuffer(b *types.Buffer) error {
 
 	v.ChannelCall("listener_remove", b.Listener)
 	delete(v.buffers, b.Num)
+	v.resetSemanticTokens(b.Num)
+	v.cancelCodeLensRequest(b.Num)
+	v.resetFoldingRanges(b.Num)
+	if err := v.forgetPulledDiagnostics(b); err != nil {
+		return err
+	}
 	params := &protocol.DidCloseTextDocumentParams{
 		TextDocument: b.ToTextDocumentIdentifier(),
 	}
@@ -291,7 +337,9 @@ func (v *vimstate) bufWritePost(args ...json.RawMessage) error {
 	if err := v.server.DidSave(context.Background(), params); err != nil {
 		return fmt.Errorf("failed to call gopls.DidSave on %v: %v", cb.Name, err)
 	}
-	return nil
+	// Saving can change the diagnostics of any buffer, e.g. those of other
+	// packages
+	return v.refreshPullDiagnostics()
 }
 
 type bufferUpdate struct {
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/govim/govim"
+	"github.com/govim/govim/cmd/govim/internal/golang_org_x_tools_gopls/protocol"
+)
+
+type callHierarchyDirection int
+
+const (
+	callHierarchyIncoming callHierarchyDirection = iota
+	callHierarchyOutgoing
+)
+
+func (v *vimstate) callersOf(flags govim.CommandFlags, args ...string) error {
+	return v.callHierarchy(flags, callHierarchyIncoming)
+}
+
+func (v *vimstate) calleesOf(flags govim.CommandFlags, args ...string) error {
+	return v.callHierarchy(flags, callHierarchyOutgoing)
+}
+
+// callHierarchy opens a tree view of the calls to (incoming) or from
+// (outgoing) the function under the cursor. When called with a bang, the
+// first level of calls is put in the quickfix list instead.
+func (v *vimstate) callHierarchy(flags govim.CommandFlags, dir callHierarchyDirection) error {
+	b, pos, err := v.bufCursorPos()
+	if err != nil {
+		return fmt.Errorf("failed to get current position: %v", err)
+	}
+	params := &protocol.CallHierarchyPrepareParams{
+		TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+			TextDocument: b.ToTextDocumentIdentifier(),
+			Position:     pos.ToPosition(),
+		},
+	}
+	items, err := v.server.PrepareCallHierarchy(context.Background(), params)
+	if err != nil {
+		return fmt.Errorf("call to gopls.PrepareCallHierarchy failed: %v", err)
+	}
+	if len(items) == 0 {
+		return fmt.Errorf("no function found under cursor")
+	}
+
+	var roots []*treeNode
+	for _, item := range items {
+		loc := protocol.Location{URI: item.URI, Range: item.SelectionRange}
+		roots = append(roots, v.callHierarchyNode(item, dir, []protocol.Location{loc}))
+	}
+
+	if flags.Bang != nil && *flags.Bang {
+		var locs []protocol.Location
+		for _, r := range roots {
+			if err := v.expandTreeNode(r); err != nil {
+				return err
+			}
+			locs = append(locs, r.locs...)
+			for _, c := range r.children {
+				locs = append(locs, c.locs...)
+			}
+		}
+		v.populateQuickfix(locs, len(roots) == 1)
+		return nil
+	}
+
+	title := "Callers of " + items[0].Name
+	kind := "callers"
+	if dir == callHierarchyOutgoing {
+		title = "Callees of " + items[0].Name
+		kind = "callees"
+	}
+	_, err = v.openTreeView(flags.Mods, "below 15split", kind, title, roots)
+	return err
+}
+
+// callHierarchyNode creates a tree node for item. locs are the call sites
+// that relate item to its parent node; selecting the node jumps to the first
+// of them.
+func (v *vimstate) callHierarchyNode(item protocol.CallHierarchyItem, dir callHierarchyDirection, locs []protocol.Location) *treeNode {
+	text := fmt.Sprintf("%s  %s", item.Name, v.relLocation(locs[0]))
+	if len(locs) > 1 {
+		text += fmt.Sprintf(" (%d calls)", len(locs))
+	}
+	n := &treeNode{
+		text: text,
+		locs: locs,
+	}
+	n.expand = func() ([]*treeNode, error) {
+		var children []*treeNode
+		switch dir {
+		case callHierarchyIncoming:
+			calls, err := v.server.IncomingCalls(context.Background(), &protocol.CallHierarchyIncomingCallsParams{Item: item})
+			if err != nil {
+				return nil, fmt.Errorf("call to gopls.IncomingCalls failed: %v", err)
+			}
+			for _, c := range calls {
+				var locs []protocol.Location
+				for _, r := range c.FromRanges {
+					locs = append(locs, protocol.Location{URI: c.From.URI, Range: r})
+				}
+				if len(locs) == 0 {
+					locs = append(locs, protocol.Location{URI: c.From.URI, Range: c.From.SelectionRange})
+				}
+				children = append(children, v.callHierarchyNode(c.From, dir, locs))
+			}
+		case callHierarchyOutgoing:
+			calls, err := v.server.OutgoingCalls(context.Background(), &protocol.CallHierarchyOutgoingCallsParams{Item: item})
+			if err != nil {
+				return nil, fmt.Errorf("call to gopls.OutgoingCalls failed: %v", err)
+			}
+			for _, c := range calls {
+				// For outgoing calls the call sites are found in the caller, i.e.
+				// item, not in the callee
+				var locs []protocol.Location
+				for _, r := range c.FromRanges {
+					locs = append(locs, protocol.Location{URI: item.URI, Range: r})
+				}
+				if len(locs) == 0 {
+					locs = append(locs, protocol.Location{URI: c.To.URI, Range: c.To.SelectionRange})
+				}
+				children = append(children, v.callHierarchyNode(c.To, dir, locs))
+			}
+		}
+		sort.SliceStable(children, func(i, j int) bool {
+			lhs, rhs := children[i].locs[0], children[j].locs[0]
+			if lhs.URI != rhs.URI {
+				return lhs.URI < rhs.URI
+			}
+			return protocol.ComparePosition(lhs.Range.Start, rhs.Range.Start) < 0
+		})
+		return children, nil
+	}
+	return n
+}
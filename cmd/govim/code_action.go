@@ -6,11 +6,96 @@ import (
 	"math/rand"
 
 	"github.com/govim/govim"
+	"github.com/govim/govim/cmd/govim/config"
 	"github.com/govim/govim/cmd/govim/internal/golang_org_x_tools_gopls/protocol"
 	"github.com/govim/govim/cmd/govim/internal/golang_org_x_tools_gopls/settings"
 	"github.com/govim/govim/cmd/govim/internal/types"
 )
 
+// codeAction shows the code actions available at the cursor, or for the
+// range, in a popup menu. The selected action is resolved if needed, and its
+// edit applied and/or command executed via popupSelection. An optional
+// argument restricts the actions to those of a kind, e.g. "refactor.extract".
+func (v *vimstate) codeAction(flags govim.CommandFlags, args ...string) error {
+	b, pos, err := v.bufCursorPos()
+	if err != nil {
+		return fmt.Errorf("failed to get cursor position: %v", err)
+	}
+	start, end := *pos.Point, *pos.Point
+	if *flags.Range != 0 {
+		start, end, err = v.rangeFromFlags(b, flags)
+		if err != nil {
+			return err
+		}
+	}
+	trigger := protocol.CodeActionInvoked
+	params := &protocol.CodeActionParams{
+		TextDocument: b.ToTextDocumentIdentifier(),
+		Range: protocol.Range{
+			Start: start.ToPosition(),
+			End:   end.ToPosition(),
+		},
+		Context: protocol.CodeActionContext{
+			Diagnostics: v.diagnosticsCoveringLines(b, start.ToPosition().Line, end.ToPosition().Line),
+			TriggerKind: &trigger,
+		},
+	}
+	if len(args) == 1 {
+		params.Context.Only = []protocol.CodeActionKind{protocol.CodeActionKind(args[0])}
+	}
+	actions, err := v.server.CodeAction(context.Background(), params)
+	if err != nil {
+		return fmt.Errorf("codeAction failed: %v", err)
+	}
+
+	var fixes []suggestedFix
+	var titles []string
+	for _, ca := range actions {
+		if ca.Disabled != nil {
+			continue
+		}
+		fixes = append(fixes, newSuggestedFix(ca))
+		titles = append(titles, ca.Title)
+	}
+	if len(fixes) == 0 {
+		v.ChannelEx(`echom "No code actions available"`)
+		return nil
+	}
+
+	// Only one menu of fixes can be open at a time
+	for pid := range v.suggestedFixesPopups {
+		v.ChannelCall("popup_close", pid)
+		delete(v.suggestedFixesPopups, pid)
+	}
+	opts := map[string]interface{}{
+		"line":       "cursor+1",
+		"col":        "cursor",
+		"drag":       1,
+		"mapping":    0,
+		"cursorline": 1,
+		"filter":     "popup_filter_menu",
+		"title":      "Code actions",
+		"callback":   "g:GOVIM" + config.FunctionPopupSelection,
+	}
+	popupID := v.ParseInt(v.ChannelCall("popup_create", titles, opts))
+	v.suggestedFixesPopups[popupID] = fixes
+	return nil
+}
+
+// codeActionNeedsResolve returns true if ca was returned without its edit or
+// command, which must then be resolved via gopls.ResolveCodeAction.
+func codeActionNeedsResolve(ca protocol.CodeAction) bool {
+	return ca.Edit == nil && ca.Command == nil && ca.Data != nil
+}
+
+func (v *vimstate) resolveCodeAction(ca protocol.CodeAction) (*protocol.CodeAction, error) {
+	res, err := v.server.ResolveCodeAction(context.Background(), &ca)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve code action %q: %v", ca.Title, err)
+	}
+	return res, nil
+}
+
 func (v *vimstate) runGoTest(flags govim.CommandFlags, args ...string) error {
 	if c := v.config.ExperimentalProgressPopups; c == nil || !*c {
 		opts := make(map[string]interface{})
@@ -3,10 +3,15 @@ package main
 import (
 	"context"
 	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
 
 	"github.com/govim/govim"
+	"github.com/govim/govim/cmd/govim/config"
 	"github.com/govim/govim/cmd/govim/internal/golang_org_x_tools_gopls/protocol"
 	"github.com/govim/govim/cmd/govim/internal/golang_org_x_tools_gopls/protocol/command"
+	"github.com/govim/govim/cmd/govim/internal/types"
 )
 
 // toggleGCDetails calls gopls CommandToggleDetails (via CodeLens) that enable/disable
@@ -48,3 +53,223 @@ func (v *vimstate) toggleGCDetails(flags govim.CommandFlags, args ...string) err
 	}
 	return nil
 }
+
+// codeLens runs the code lens on the cursor line. If there is more than one,
+// the lens to run is chosen from a popup menu.
+func (v *vimstate) codeLens(flags govim.CommandFlags, args ...string) error {
+	cb, pos, err := v.bufCursorPos()
+	if err != nil {
+		return fmt.Errorf("failed to determine cursor position: %v", err)
+	}
+	res, err := v.server.CodeLens(context.Background(), &protocol.CodeLensParams{
+		TextDocument: cb.ToTextDocumentIdentifier(),
+	})
+	if err != nil {
+		return fmt.Errorf("codeLens failed: %v", err)
+	}
+	var lenses []protocol.CodeLens
+	for _, l := range res {
+		if l.Command != nil && l.Range.Start.Line == uint32(pos.Line()-1) {
+			lenses = append(lenses, l)
+		}
+	}
+	switch len(lenses) {
+	case 0:
+		v.ChannelEx(`echom "No code lenses on this line"`)
+		return nil
+	case 1:
+		return v.runCodeLens(lenses[0].Command)
+	}
+
+	// Only one menu of fixes can be open at a time
+	for pid := range v.suggestedFixesPopups {
+		v.ChannelCall("popup_close", pid)
+		delete(v.suggestedFixesPopups, pid)
+	}
+	var fixes []suggestedFix
+	var titles []string
+	for _, l := range sortedCodeLenses(lenses) {
+		fixes = append(fixes, suggestedFix{
+			msg:     l.Command.Title,
+			command: l.Command,
+			lens:    true,
+		})
+		titles = append(titles, l.Command.Title)
+	}
+	opts := map[string]interface{}{
+		"line":       "cursor+1",
+		"col":        "cursor",
+		"drag":       1,
+		"mapping":    0,
+		"cursorline": 1,
+		"filter":     "popup_filter_menu",
+		"title":      "Code lenses",
+		"callback":   "g:GOVIM" + config.FunctionPopupSelection,
+	}
+	popupID := v.ParseInt(v.ChannelCall("popup_create", titles, opts))
+	v.suggestedFixesPopups[popupID] = fixes
+	return nil
+}
+
+// runCodeLens executes the command of a code lens. If progress popups are
+// enabled, the progress reported by gopls whilst running the command is shown
+// in a progress popup.
+func (v *vimstate) runCodeLens(cmd *protocol.Command) error {
+	var token protocol.ProgressToken
+	if c := v.config.ExperimentalProgressPopups; c != nil && *c {
+		initiator := types.CodeLens
+		switch cmd.Command {
+		case command.Test.String(), command.RunTests.String():
+			// Highlight the popup according to the test results, as for
+			// CommandGoTest
+			initiator = types.GoTest
+		}
+		token = protocol.ProgressToken(fmt.Sprintf("govim%d", rand.Uint64()))
+		if _, ok := v.progressPopups[token]; ok {
+			return fmt.Errorf("failed to init progress, duplicate token")
+		}
+		v.progressPopups[token] = &types.ProgressPopup{Initiator: initiator}
+	}
+	if err := v.executeCommand(cmd, token); err != nil {
+		if p, ok := v.progressPopups[token]; ok && p.ID == 0 {
+			// No progress was reported, so there is no popup to close
+			delete(v.progressPopups, token)
+		}
+		return err
+	}
+	return nil
+}
+
+// codeLensesEnabled returns true if code lenses should be shown, and Vim
+// supports virtual text above a line.
+func (v *vimstate) codeLensesEnabled() bool {
+	return v.hasVirtualTextAbove && v.config.ShowCodeLenses != nil && *v.config.ShowCodeLenses
+}
+
+// updateAllCodeLenses updates the code lenses of all loaded buffers
+func (v *vimstate) updateAllCodeLenses() error {
+	for _, b := range v.buffers {
+		v.updateCodeLenses(b)
+	}
+	return nil
+}
+
+// updateCodeLenses requests the code lenses of b in the background, and
+// renders them once the response has arrived. Any ongoing request for b is
+// cancelled so that only the latest response is rendered.
+func (v *vimstate) updateCodeLenses(b *types.Buffer) {
+	v.cancelCodeLensRequest(b.Num)
+	if !v.codeLensesEnabled() || !b.Loaded {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	v.cancelCodeLenses[b.Num] = cancel
+	version := b.Version
+	v.tomb.Go(func() error {
+		lenses, err := v.server.CodeLens(ctx, &protocol.CodeLensParams{
+			TextDocument: b.ToTextDocumentIdentifier(),
+		})
+		select {
+		case <-ctx.Done():
+			return nil
+		default:
+		}
+		v.govimplugin.Schedule(func(govim.Govim) error {
+			// A newer request has or will soon be sent, so this response is no
+			// longer relevant
+			select {
+			case <-ctx.Done():
+				return nil
+			default:
+			}
+			delete(v.cancelCodeLenses, b.Num)
+			if err != nil {
+				v.Logf("codeLens call failed for %v: %v", b.Name, err)
+				return nil
+			}
+			if cb, ok := v.buffers[b.Num]; !ok || cb != b || !b.Loaded || b.Version != version {
+				return nil
+			}
+			v.renderCodeLenses(b, lenses)
+			return nil
+		})
+		return nil
+	})
+}
+
+// renderCodeLenses replaces the code lenses of b with lenses. The titles of
+// the lenses of each line are shown as a single virtual text above the line.
+func (v *vimstate) renderCodeLenses(b *types.Buffer, lenses []protocol.CodeLens) {
+	var lines []int
+	titles := make(map[int][]string)
+	for _, l := range sortedCodeLenses(lenses) {
+		if l.Command == nil {
+			continue
+		}
+		p, err := types.PointFromPosition(b, l.Range.Start)
+		if err != nil {
+			v.Logf("failed to convert code lens position %v to point: %v", l.Range.Start, err)
+			continue
+		}
+		if _, ok := titles[p.Line()]; !ok {
+			lines = append(lines, p.Line())
+		}
+		titles[p.Line()] = append(titles[p.Line()], l.Command.Title)
+	}
+	v.BatchStart()
+	defer v.BatchCancelIfNotEnded()
+	v.batchRemoveCodeLenses(b.Num)
+	for _, line := range lines {
+		v.BatchAssertChannelCall(assertPropAdd, "prop_add", line, 0, propAddTextDict{
+			Type:      string(config.HighlightCodeLens),
+			Text:      strings.Join(titles[line], " | "),
+			TextAlign: "above",
+			BufNr:     b.Num,
+		})
+	}
+	v.MustBatchEnd()
+}
+
+// removeCodeLenses removes the code lenses from all loaded buffers
+func (v *vimstate) removeCodeLenses() {
+	v.BatchStart()
+	defer v.BatchCancelIfNotEnded()
+	for bufnr, b := range v.buffers {
+		v.cancelCodeLensRequest(bufnr)
+		if b.Loaded {
+			v.batchRemoveCodeLenses(bufnr)
+		}
+	}
+	v.MustBatchEnd()
+}
+
+func (v *vimstate) batchRemoveCodeLenses(bufnr int) {
+	v.BatchChannelCall("prop_remove", struct {
+		Type  string `json:"type"`
+		BufNr int    `json:"bufnr"`
+		All   int    `json:"all"`
+	}{string(config.HighlightCodeLens), bufnr, 1})
+}
+
+// cancelCodeLensRequest cancels the ongoing code lens request for the buffer
+// bufnr, if any.
+func (v *vimstate) cancelCodeLensRequest(bufnr int) {
+	if cancel, ok := v.cancelCodeLenses[bufnr]; ok {
+		cancel()
+		delete(v.cancelCodeLenses, bufnr)
+	}
+}
+
+// sortedCodeLenses returns lenses sorted by position, such that lenses on the
+// same line are shown in the order they appear in the line.
+func sortedCodeLenses(lenses []protocol.CodeLens) []protocol.CodeLens {
+	res := append([]protocol.CodeLens(nil), lenses...)
+	sort.SliceStable(res, func(i, j int) bool {
+		a, b := res[i].Range.Start, res[j].Range.Start
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Character < b.Character
+	})
+	return res
+}
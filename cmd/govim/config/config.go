@@ -76,6 +76,19 @@ type Config struct {
 	// Default: true
 	QuickfixSigns *bool `json:",omitempty"`
 
+	// LocationListDiagnostics is a boolean (0 or 1 in VimScript) that controls
+	// whether the location list of each window is populated with the gopls
+	// diagnostics of the buffer shown in the window. The location lists are
+	// updated as diagnostics change and as buffers are shown in windows. As
+	// with the quickfix window, a location list is only populated if it is
+	// empty or already holds diagnostics, such that the results of e.g.
+	// :lvimgrep are not replaced. This is independent of
+	// QuickfixAutoDiagnostics, which can be disabled to leave the quickfix
+	// list to other commands.
+	//
+	// Default: false
+	LocationListDiagnostics *bool `json:",omitempty"`
+
 	// HighlightDiagnostics enables in-code highlighting of diagnostics using
 	// text properties. Each diagnostic reported by gopls will be highlighted
 	// according to it's severity, using the following vim defined highlight
@@ -84,6 +97,25 @@ type Config struct {
 	// Default: true
 	HighlightDiagnostics *bool `json:",omitempty"`
 
+	// DiagnosticsVirtualText is a string value that controls whether the
+	// messages of diagnostics are shown as virtual text after the end of the
+	// lines they apply to, using the same highlight groups as
+	// HighlightDiagnostics. Where a line has several diagnostics, the message
+	// of the most severe is shown along with the number of others. Options
+	// are given by constants of type DiagnosticsVirtualText. Virtual text
+	// after a line requires Vim v9.0.0200 or later.
+	//
+	// Default: DiagnosticsVirtualTextOff
+	DiagnosticsVirtualText *DiagnosticsVirtualText `json:",omitempty"`
+
+	// DiagnosticsVirtualTextMaxWidth is the maximum number of characters of a
+	// message shown as virtual text, see DiagnosticsVirtualText. Longer
+	// messages are truncated and end in "…". A value of 0 means messages are
+	// only truncated at the edge of the window.
+	//
+	// Default: 80
+	DiagnosticsVirtualTextMaxWidth *int `json:",omitempty"`
+
 	// HighlightReferences is a boolean (0 or 1 in VimScript) that controls
 	// whether references to what is currently under the cursor should be
 	// highlighted. When enabled, govim waits for updatetime (help updatetime)
@@ -95,6 +127,16 @@ type Config struct {
 	// Default: true
 	HighlightReferences *bool `json:",omitempty"`
 
+	// HighlightSemanticTokens is a boolean (0 or 1 in VimScript) that controls
+	// whether semantic tokens reported by gopls should be highlighted. When
+	// enabled, each token is highlighted using text properties according to
+	// its type and modifiers, e.g. GOVIMSemanticNamespace for package names
+	// or GOVIMSemanticModDeprecated for deprecated symbols. Each highlight
+	// group can be overridden in vimrc; see HighlightSemanticPrefix.
+	//
+	// Default: false
+	HighlightSemanticTokens *bool `json:",omitempty"`
+
 	// HoverDiagnostics is a boolean (0 or 1 in VimScript) that controls
 	// whether diagnostics should be shown in the hover popup. When enabled
 	// each diagnostic that covers the cursor/mouse position will be added
@@ -108,6 +150,40 @@ type Config struct {
 	// Default: true
 	HoverDiagnostics *bool `json:",omitempty"`
 
+	// DiagnosticsMinSeverity is a string value that sets the minimum severity
+	// of the diagnostics govim shows, i.e. those in the quickfix window and
+	// location lists, signs, highlights and hover popups. Options are given
+	// by constants of type DiagnosticSeverity. DiagnosticsMinSeverityBySource
+	// and DiagnosticsMinSeverityByPath set further minimums; a diagnostic is
+	// only shown if it meets every minimum that applies to it.
+	//
+	// Default: DiagnosticSeverityHint
+	DiagnosticsMinSeverity *DiagnosticSeverity `json:",omitempty"`
+
+	// DiagnosticsMinSeverityBySource is a map from diagnostic sources to the
+	// minimum severity of the diagnostics shown from those sources. Keys are
+	// patterns as understood by path.Match, matched against the source
+	// reported by gopls, e.g. "compiler", the name of an analyzer or the code
+	// of a staticcheck check. DiagnosticSeverityNone hides all diagnostics
+	// from a source.
+	//
+	// Example: govim#config#Set("DiagnosticsMinSeverityBySource", {"SA*": "warning", "unusedparams": "none"})
+	//
+	// Default: nil
+	DiagnosticsMinSeverityBySource *map[string]DiagnosticSeverity `json:",omitempty"`
+
+	// DiagnosticsMinSeverityByPath is a map from file paths to the minimum
+	// severity of the diagnostics shown in those files. Keys are patterns as
+	// understood by path.Match, matched against the slash-separated path of a
+	// file relative to the working directory, and against each of its parent
+	// directories. A pattern without a slash is also matched against the base
+	// name of the file.
+	//
+	// Example: govim#config#Set("DiagnosticsMinSeverityByPath", {"vendor": "none", "*_gen.go": "warning"})
+	//
+	// Default: nil
+	DiagnosticsMinSeverityByPath *map[string]DiagnosticSeverity `json:",omitempty"`
+
 	// CompletionDeepCompletiions enables gopls' deep completion option
 	// in the derivation of completion candidates.
 	//
@@ -186,6 +262,58 @@ type Config struct {
 	// Default: nil
 	Analyses *map[string]bool `json:",omitempty"`
 
+	// InlayHints is a map of booleans (0 or 1 in VimScript) used to enable
+	// specific kinds of gopls inlay hints, e.g. parameter names or the types
+	// of variables in assignments. Enabled hints are requested for the
+	// visible part of each Go buffer and shown as virtual text using the
+	// GOVIMInlayHint highlight group. Virtual text requires Vim v9.0.0067 or
+	// later. A list of hint kinds can be found in the gopls documentation
+	// (e.g.
+	// https://github.com/golang/tools/blob/master/gopls/doc/inlayHints.md for
+	// master).
+	//
+	// Example: govim#config#Set("InlayHints", {"parameterNames": 1, "assignVariableTypes": 1})
+	//
+	// Default: nil
+	InlayHints *map[string]bool `json:",omitempty"`
+
+	// CodeLenses is a map of booleans (0 or 1 in VimScript) used to enable or
+	// disable specific sources of gopls code lenses, e.g. "test" for running
+	// tests and benchmarks or "tidy" for running go mod tidy from a go.mod
+	// file. The map is merged with the gopls defaults, except that
+	// "gc_details" is always enabled unless explicitly disabled since
+	// CommandGCDetails depends on it. A list of sources can be found in the
+	// gopls documentation (e.g.
+	// https://github.com/golang/tools/blob/master/gopls/doc/codelenses.md for
+	// master).
+	//
+	// Example: govim#config#Set("CodeLenses", {"test": 1, "run_govulncheck": 1})
+	//
+	// Default: nil
+	CodeLenses *map[string]bool `json:",omitempty"`
+
+	// ShowCodeLenses is a boolean (0 or 1 in VimScript) that controls whether
+	// the code lenses of each loaded buffer are shown as virtual text above
+	// the line they apply to, using the GOVIMCodeLens highlight group. A lens
+	// is run with CommandCodeLens. Virtual text above a line requires Vim
+	// v9.0.0200 or later.
+	//
+	// Default: false
+	ShowCodeLenses *bool `json:",omitempty"`
+
+	// FoldingRanges is a boolean (0 or 1 in VimScript) that controls whether
+	// Go buffers are folded according to the folding ranges reported by
+	// gopls, e.g. for function bodies, composite literals, import blocks and
+	// comment blocks. When enabled, govim computes the fold level of each line
+	// whenever a buffer changes, and GOVIMFoldExpr() returns that level. govim
+	// sets foldexpr=GOVIMFoldExpr() for Go buffers, hence folding is enabled
+	// via foldmethod=expr, e.g.:
+	//
+	//    autocmd FileType go setlocal foldmethod=expr
+	//
+	// Default: false
+	FoldingRanges *bool `json:",omitempty"`
+
 	// OpenLastProgressWith configures how vim should open the buffer created
 	// when calling :GOVIMLastProgress.
 	// Valid values are any vim command that takes a buffer number as argument.
@@ -200,6 +328,59 @@ type Config struct {
 	// Default: "below 10split"
 	OpenLastProgressWith *string `json:",omitempty"`
 
+	// OpenExternalWith is the command used to open URLs that gopls asks to be
+	// shown by an external program, e.g. package documentation or compiler
+	// optimisation details in a browser. The URL is passed as the last
+	// argument. When empty, the URL is instead copied to the unnamed register,
+	// and to the + register if Vim has clipboard support.
+	//
+	// Default: "xdg-open"
+	OpenExternalWith *string `json:",omitempty"`
+
+	// MessageRequestTimeout is the string-format time.Duration for which govim
+	// waits for the user to choose an action in response to a prompt from
+	// gopls, e.g. whether to run go mod tidy. The prompt is shown in a popup
+	// menu, in which <CR> chooses the selected action and <Esc> dismisses the
+	// prompt. When the timeout expires the popup is closed as if dismissed.
+	// A prompt sent whilst a command is executed, e.g. that of a code lens, is
+	// instead shown with inputlist(), which the timeout does not close. Zero
+	// means no timeout.
+	//
+	// Default: "1m"
+	MessageRequestTimeout *string `json:",omitempty"`
+
+	// MultiFileEditStrategy is a string value that controls how govim edits
+	// files that are not shown in a window when applying edits to several
+	// files, e.g. those of CommandRename, CommandFixAll and code actions.
+	// Options are given by constants of type MultiFileEditStrategy.
+	//
+	// Default: MultiFileEditStrategySplit
+	MultiFileEditStrategy *MultiFileEditStrategy `json:",omitempty"`
+
+	// MultiFileEditAutoSave is a boolean (0 or 1 in VimScript) that controls
+	// whether the buffers changed by an edit of several files are written
+	// once the edit has been applied.
+	//
+	// Default: false
+	MultiFileEditAutoSave *bool `json:",omitempty"`
+
+	// MultiFileEditQuickfix is a boolean (0 or 1 in VimScript) that controls
+	// whether a summary of the changes made by an edit of several files is
+	// added to the quickfix list, one entry per change. The summary is a new
+	// quickfix list, so :colder returns to the previous list.
+	//
+	// Default: false
+	MultiFileEditQuickfix *bool `json:",omitempty"`
+
+	// MultipleLocations is a string value that controls how govim presents
+	// the results of CommandGoToDef, CommandGoToTypeDef and CommandGoToDecl
+	// when gopls returns more than one location, e.g. for an import of a
+	// package with several files. Options are given by constants of type
+	// MultipleLocations.
+	//
+	// Default: MultipleLocationsPopup
+	MultipleLocations *MultipleLocations `json:",omitempty"`
+
 	// Gofumpt configures gopls to use gofumpt as formatter.
 	// It is a stricter formatter than gofmt, while being backwards compatible.
 	// Read more at: https://github.com/mvdan/gofumpt
@@ -308,6 +489,25 @@ type Config struct {
 	//
 	// Default: false
 	ExperimentalAllowModfileModifications *bool `json:",omitempty"`
+
+	// ExperimentalPullDiagnostics controls whether govim pulls diagnostics
+	// from gopls via textDocument/diagnostic, instead of relying on the
+	// diagnostics gopls publishes. When enabled, the diagnostics of a buffer
+	// are requested when it is loaded and whenever it changes, and those of
+	// all loaded buffers are requested when a buffer is written or when gopls
+	// asks for them to be refreshed. This limits the diagnostics gopls
+	// computes to the buffers being edited, which can help in very large
+	// workspaces. The quickfix window, signs and highlights work as normal.
+	//
+	// This option is read when gopls is started, i.e. changing it requires
+	// a restart of Vim. It has no effect if gopls does not support pull
+	// diagnostics.
+	//
+	// This is an experimental feature that might go away in the future, be
+	// renamed etc.
+	//
+	// Default: false
+	ExperimentalPullDiagnostics *bool `json:",omitempty"`
 }
 
 type Command string
@@ -324,9 +524,28 @@ const (
 	CommandGoToTypeDef Command = "GoToTypeDef"
 
 	// CommandGoToPrevDef jumps to the previous location in the jump stack.
-	// CommandGoToPrevDef respects &switchbuf
+	// Each tab page has its own jump stack. CommandGoToPrevDef respects
+	// &switchbuf
 	CommandGoToPrevDef Command = "GoToPrevDef"
 
+	// CommandGoToNextDef jumps forward through the jump stack, to the
+	// location that CommandGoToPrevDef last returned from. CommandGoToNextDef
+	// respects &switchbuf
+	CommandGoToNextDef Command = "GoToNextDef"
+
+	// CommandJumpStack opens a popup that lists the entries of the jump stack
+	// of the current tab page, with the name of the symbol jumped to and the
+	// file and line jumped from. Selecting an entry returns to where it was
+	// jumped from. Entries follow edits to the files they are in.
+	CommandJumpStack Command = "JumpStack"
+
+	// CommandGoToDecl jumps to the declaration of the identifier under the
+	// cursor, pushing the current location onto the jump stack. In Go the
+	// declaration of an identifier is its definition, so where gopls does not
+	// support declarations CommandGoToDecl behaves as CommandGoToDef.
+	// CommandGoToDecl respects &switchbuf
+	CommandGoToDecl Command = "GoToDecl"
+
 	// CommandGoFmt applies gofmt to the entire buffer
 	CommandGoFmt Command = "GoFmt"
 
@@ -347,7 +566,8 @@ const (
 
 	// CommandRename renames the identifier under the cursor. If provided with an
 	// argument, that argument is used as the new name. If not, the user is
-	// prompted for the new identifier name.
+	// prompted for the new identifier name. With a bang, a diff of the changes
+	// is previewed and they are only applied if the user confirms them.
 	CommandRename Command = "Rename"
 
 	// CommandStringFn applies a transformation function to text. Without a
@@ -406,6 +626,96 @@ const (
 	// Open a new buffer that contain the current output from the most recently
 	// created progress popup. Useful for looking at a failed test for example.
 	CommandLastProgress Command = "LastProgress"
+
+	// CommandCallersOf opens a tree of the callers of the function under the
+	// cursor in a scratch buffer. Deeper levels of the tree are fetched as they
+	// are expanded. Within the tree, <CR> jumps to the call site under the
+	// cursor, "o" or "za" expands/collapses a node, "O" expands a node
+	// recursively, "Q" populates the quickfix list with every call site
+	// currently shown and "q" closes the tree.
+	//
+	// Calling "GOVIMCallersOf!" populates the quickfix list with the direct
+	// callers instead of opening a tree.
+	CommandCallersOf Command = "CallersOf"
+
+	// CommandCalleesOf is the outgoing counterpart of CommandCallersOf: it
+	// opens a tree of the functions called by the function under the cursor.
+	CommandCalleesOf Command = "CalleesOf"
+
+	// CommandTypeHierarchy opens a tree of the supertypes and subtypes of the
+	// type under the cursor in a vertical split. Nodes can be expanded
+	// recursively using the same mappings as CommandCallersOf. An optional
+	// argument of "supertypes" or "subtypes" restricts the tree to one
+	// direction.
+	CommandTypeHierarchy Command = "TypeHierarchy"
+
+	// CommandCodeAction shows the code actions available at the cursor, or for
+	// the range, in a popup menu, e.g. refactorings and organizing imports.
+	// The selected action is applied. An optional argument restricts the
+	// actions shown to those of a kind, e.g. "refactor.extract".
+	CommandCodeAction Command = "CodeAction"
+
+	// CommandExtractFunction extracts the statements in the range, or on the
+	// current line, to a new function, and then renames the function as
+	// CommandRename does. An optional argument is the name of the function,
+	// without which the user is prompted for a name.
+	CommandExtractFunction Command = "ExtractFunction"
+
+	// CommandExtractMethod is the method counterpart of
+	// CommandExtractFunction.
+	CommandExtractMethod Command = "ExtractMethod"
+
+	// CommandExtractVariable extracts the expression in the range to a new
+	// variable, or a constant for a constant expression, and then renames the
+	// variable as CommandExtractFunction does.
+	CommandExtractVariable Command = "ExtractVariable"
+
+	// CommandOutline toggles a sidebar that lists the symbols of the current
+	// buffer hierarchically. The entry that contains the cursor is highlighted
+	// as the cursor moves, and the sidebar is refreshed as the buffer changes.
+	// When the cursor moves to another Go buffer, the sidebar switches to that
+	// buffer. Entries can be navigated using the same mappings as
+	// CommandCallersOf.
+	CommandOutline Command = "Outline"
+
+	// CommandSymbols opens a popup to search for workspace symbols. Results
+	// are updated as the query is typed, and are matched and qualified
+	// according to SymbolMatcher and SymbolStyle. Within the popup, <C-n>/<Down>
+	// and <C-p>/<Up> move the selection, <CR> jumps to the selected symbol and
+	// <Esc> closes the popup. Any arguments are used as the initial query.
+	CommandSymbols Command = "Symbols"
+
+	// CommandCodeLens runs the code lens on the cursor line, e.g. to run a
+	// test or to tidy a go.mod file. If there are several lenses on the line,
+	// the lens to run is chosen from a popup menu. Enable progress popups to
+	// see the progress of long running lenses.
+	CommandCodeLens Command = "CodeLens"
+
+	// CommandDiagnosticNext moves the cursor to the next diagnostic in the
+	// current buffer, wrapping around at the end of the buffer, and shows its
+	// message in the hover popup. A count moves that many diagnostics. An
+	// optional argument of type DiagnosticSeverity skips diagnostics that are
+	// less severe, e.g. "error" moves between errors only. With a bang the
+	// cursor moves across files, in the order of the quickfix window.
+	CommandDiagnosticNext Command = "DiagnosticNext"
+
+	// CommandDiagnosticPrev is the counterpart of CommandDiagnosticNext that
+	// moves to the previous diagnostic.
+	CommandDiagnosticPrev Command = "DiagnosticPrev"
+
+	// CommandDiagnosticRelated moves the cursor to a location related to the
+	// diagnostics under the cursor, e.g. that of a conflicting declaration. A
+	// count selects the location of that number, as listed in the hover
+	// popup, and defaults to the first.
+	CommandDiagnosticRelated Command = "DiagnosticRelated"
+
+	// CommandFixAll applies the suggested fixes of all diagnostics reported by
+	// an analyzer, given as argument, across all files with diagnostics, e.g.
+	// "fillreturns" or "unusedvariable". Fixes that conflict with those
+	// already applied are skipped. A summary of the edited files is shown.
+	// With a bang the fixes are not applied; instead a diff of them is opened
+	// in a split.
+	CommandFixAll Command = "FixAll"
 )
 
 type Function string
@@ -444,10 +754,59 @@ const (
 
 	FunctionProgressClosed Function = InternalFunctionPrefix + "ProgressClosed"
 
+	// FunctionTreeAction is an internal function used by govim to handle key
+	// mappings in tree view buffers, e.g. CommandCallersOf
+	FunctionTreeAction Function = InternalFunctionPrefix + "TreeAction"
+
 	// FunctionStringFnComplete is an internal function used by govim to provide
 	// completion of arguments to CommandStringFn
 	FunctionStringFnComplete Function = InternalFunctionPrefix + "StringFnComplete"
 
+	// FunctionTypeHierarchyComplete is an internal function used by govim to
+	// provide completion of arguments to CommandTypeHierarchy
+	FunctionTypeHierarchyComplete Function = InternalFunctionPrefix + "TypeHierarchyComplete"
+
+	// FunctionDiagnosticSeverityComplete is an internal function used by
+	// govim to provide completion of arguments to CommandDiagnosticNext and
+	// CommandDiagnosticPrev
+	FunctionDiagnosticSeverityComplete Function = InternalFunctionPrefix + "DiagnosticSeverityComplete"
+
+	// FunctionFixAllComplete is an internal function used by govim to provide
+	// completion of arguments to CommandFixAll
+	FunctionFixAllComplete Function = InternalFunctionPrefix + "FixAllComplete"
+
+	// FunctionSymbolsQuery is an internal function used by govim to update
+	// the results of CommandSymbols when the query changes
+	FunctionSymbolsQuery Function = InternalFunctionPrefix + "SymbolsQuery"
+
+	// FunctionSymbolsSelection is an internal function used by govim to jump to
+	// the symbol selected in the CommandSymbols popup
+	FunctionSymbolsSelection Function = InternalFunctionPrefix + "SymbolsSelection"
+
+	// FunctionLocationsSelection is an internal function used by govim to jump
+	// to the location selected in the popup of multiple locations
+	FunctionLocationsSelection Function = InternalFunctionPrefix + "LocationsSelection"
+
+	// FunctionJumpStackSelection is an internal function used by govim to jump
+	// to the entry selected in the CommandJumpStack popup
+	FunctionJumpStackSelection Function = InternalFunctionPrefix + "JumpStackSelection"
+
+	// FunctionMessageRequestSelection is an internal function used by govim
+	// to respond to a prompt from gopls with the action chosen in its popup
+	FunctionMessageRequestSelection Function = InternalFunctionPrefix + "MessageRequestSelection"
+
+	// FunctionDiagnosticCounts returns the number of diagnostics of each
+	// severity, as a dict with keys of type DiagnosticSeverity, in the buffer
+	// given by the optional argument (the current buffer by default) and in
+	// the whole workspace:
+	//
+	//   {"buffer": {"error": 1, "warning": 0, ...}, "workspace": {...}}
+	//
+	// Only diagnostics that are shown are counted, see DiagnosticsMinSeverity.
+	// The User autocommand GOVIMDiagnosticsChanged is fired when the
+	// diagnostics change, e.g. to redraw a statusline that shows the counts.
+	FunctionDiagnosticCounts Function = "DiagnosticCounts"
+
 	// FunctionMotion moves the cursor according to the arguments provided.
 	FunctionMotion Function = "Motion"
 
@@ -457,6 +816,89 @@ const (
 	FunctionParentCommand Function = "ParentCommand"
 )
 
+// DiagnosticSeverity typed constants define the set of valid values that
+// Config.DiagnosticsMinSeverity and the values of
+// Config.DiagnosticsMinSeverityBySource and Config.DiagnosticsMinSeverityByPath
+// can take
+type DiagnosticSeverity string
+
+const (
+	// DiagnosticSeverityError specifies that only errors are shown
+	DiagnosticSeverityError DiagnosticSeverity = "error"
+
+	// DiagnosticSeverityWarning specifies that errors and warnings are shown
+	DiagnosticSeverityWarning DiagnosticSeverity = "warning"
+
+	// DiagnosticSeverityInformation specifies that errors, warnings and
+	// information diagnostics are shown
+	DiagnosticSeverityInformation DiagnosticSeverity = "information"
+
+	// DiagnosticSeverityHint specifies that diagnostics of all severities are
+	// shown
+	DiagnosticSeverityHint DiagnosticSeverity = "hint"
+
+	// DiagnosticSeverityNone specifies that no diagnostics are shown
+	DiagnosticSeverityNone DiagnosticSeverity = "none"
+)
+
+// DiagnosticsVirtualText typed constants define the set of valid values that
+// Config.DiagnosticsVirtualText can take
+type DiagnosticsVirtualText string
+
+const (
+	// DiagnosticsVirtualTextOff specifies that messages of diagnostics are not
+	// shown as virtual text
+	DiagnosticsVirtualTextOff DiagnosticsVirtualText = "off"
+
+	// DiagnosticsVirtualTextAll specifies that messages of diagnostics are
+	// shown as virtual text for all lines
+	DiagnosticsVirtualTextAll DiagnosticsVirtualText = "all"
+
+	// DiagnosticsVirtualTextCursorLine specifies that messages of diagnostics
+	// are only shown as virtual text for the line of the cursor. The virtual
+	// text is updated once the cursor has stopped moving, i.e. after
+	// updatetime (help updatetime).
+	DiagnosticsVirtualTextCursorLine DiagnosticsVirtualText = "cursorline"
+)
+
+// MultiFileEditStrategy typed constants define the set of valid values that
+// Config.MultiFileEditStrategy can take
+type MultiFileEditStrategy string
+
+const (
+	// MultiFileEditStrategySplit specifies that files are opened in a new
+	// split, according to any command modifiers, e.g. :vertical
+	MultiFileEditStrategySplit MultiFileEditStrategy = "split"
+
+	// MultiFileEditStrategyTab specifies that files are opened in a new tab
+	// page
+	MultiFileEditStrategyTab MultiFileEditStrategy = "tab"
+
+	// MultiFileEditStrategyHidden specifies that files are loaded in hidden
+	// buffers, without changing any windows. The buffers are listed, such that
+	// they can be found with :ls
+	MultiFileEditStrategyHidden MultiFileEditStrategy = "hidden"
+
+	// MultiFileEditStrategyDisk specifies that files that are not loaded in a
+	// buffer are changed on disk directly. Loaded buffers are edited as with
+	// MultiFileEditStrategyHidden
+	MultiFileEditStrategyDisk MultiFileEditStrategy = "disk"
+)
+
+// MultipleLocations typed constants define the set of valid values that
+// Config.MultipleLocations can take
+type MultipleLocations string
+
+const (
+	// MultipleLocationsPopup specifies that the locations are listed in a
+	// popup menu, from which the location to jump to is selected
+	MultipleLocationsPopup MultipleLocations = "popup"
+
+	// MultipleLocationsQuickfix specifies that the locations are added to a
+	// new quickfix list, and that the first location is jumped to
+	MultipleLocationsQuickfix MultipleLocations = "quickfix"
+)
+
 // FormatOnSave typed constants define the set of valid values that
 // Config.FormatOnSave can take
 type FormatOnSave string
@@ -585,6 +1027,16 @@ const (
 	// HighlightHoverDiagSrc is the group used to format the source part of a hover diagnostic
 	HighlightHoverDiagSrc Highlight = "GOVIMHoverDiagSrc"
 
+	// HighlightUnnecessary is the group used to add text properties to
+	// diagnostics of unnecessary code, e.g. unused variables. It is combined
+	// with the severity highlight of the diagnostic.
+	HighlightUnnecessary Highlight = "GOVIMUnnecessary"
+
+	// HighlightDeprecated is the group used to add text properties to
+	// diagnostics of uses of deprecated identifiers. It is combined with the
+	// severity highlight of the diagnostic.
+	HighlightDeprecated Highlight = "GOVIMDeprecated"
+
 	// HighlightReferences is the group used to add text properties to references
 	HighlightReferences Highlight = "GOVIMReferences"
 
@@ -593,6 +1045,24 @@ const (
 	// HighlightSignatureParam is the group used to add text properties to the signature active parameter
 	HighlightSignatureParam Highlight = "GOVIMSignatureParam"
 
+	// HighlightSemanticPrefix is the prefix of the groups used to add text
+	// properties to semantic tokens. The group for a token type is the prefix
+	// followed by the capitalised type, e.g. GOVIMSemanticTypeParameter for
+	// "typeParameter". The group for a token modifier is the prefix followed
+	// by "Mod" and the capitalised modifier, e.g. GOVIMSemanticModReadonly
+	// for "readonly".
+	HighlightSemanticPrefix Highlight = "GOVIMSemantic"
+
+	// HighlightInlayHint is the group used to show inlay hints as virtual text
+	HighlightInlayHint Highlight = "GOVIMInlayHint"
+
+	// HighlightCodeLens is the group used to show code lenses as virtual text
+	HighlightCodeLens Highlight = "GOVIMCodeLens"
+
+	// HighlightOutlineCurrent is the group used to highlight the entry in the
+	// CommandOutline sidebar that contains the cursor
+	HighlightOutlineCurrent Highlight = "GOVIMOutlineCurrent"
+
 	// HighlightGoTestPass
 	HighlightGoTestPass Highlight = "GOVIMGoTestPass"
 	//  HighlightGoTestFail
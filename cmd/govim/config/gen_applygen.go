@@ -10,15 +10,36 @@ func (r *Config) Apply(v *Config) {
 	if v.QuickfixSigns != nil {
 		r.QuickfixSigns = v.QuickfixSigns
 	}
+	if v.LocationListDiagnostics != nil {
+		r.LocationListDiagnostics = v.LocationListDiagnostics
+	}
 	if v.HighlightDiagnostics != nil {
 		r.HighlightDiagnostics = v.HighlightDiagnostics
 	}
+	if v.DiagnosticsVirtualText != nil {
+		r.DiagnosticsVirtualText = v.DiagnosticsVirtualText
+	}
+	if v.DiagnosticsVirtualTextMaxWidth != nil {
+		r.DiagnosticsVirtualTextMaxWidth = v.DiagnosticsVirtualTextMaxWidth
+	}
 	if v.HighlightReferences != nil {
 		r.HighlightReferences = v.HighlightReferences
 	}
+	if v.HighlightSemanticTokens != nil {
+		r.HighlightSemanticTokens = v.HighlightSemanticTokens
+	}
 	if v.HoverDiagnostics != nil {
 		r.HoverDiagnostics = v.HoverDiagnostics
 	}
+	if v.DiagnosticsMinSeverity != nil {
+		r.DiagnosticsMinSeverity = v.DiagnosticsMinSeverity
+	}
+	if v.DiagnosticsMinSeverityBySource != nil {
+		r.DiagnosticsMinSeverityBySource = v.DiagnosticsMinSeverityBySource
+	}
+	if v.DiagnosticsMinSeverityByPath != nil {
+		r.DiagnosticsMinSeverityByPath = v.DiagnosticsMinSeverityByPath
+	}
 	if v.CompletionDeepCompletions != nil {
 		r.CompletionDeepCompletions = v.CompletionDeepCompletions
 	}
@@ -52,9 +73,39 @@ func (r *Config) Apply(v *Config) {
 	if v.Analyses != nil {
 		r.Analyses = v.Analyses
 	}
+	if v.InlayHints != nil {
+		r.InlayHints = v.InlayHints
+	}
+	if v.CodeLenses != nil {
+		r.CodeLenses = v.CodeLenses
+	}
+	if v.ShowCodeLenses != nil {
+		r.ShowCodeLenses = v.ShowCodeLenses
+	}
+	if v.FoldingRanges != nil {
+		r.FoldingRanges = v.FoldingRanges
+	}
 	if v.OpenLastProgressWith != nil {
 		r.OpenLastProgressWith = v.OpenLastProgressWith
 	}
+	if v.OpenExternalWith != nil {
+		r.OpenExternalWith = v.OpenExternalWith
+	}
+	if v.MessageRequestTimeout != nil {
+		r.MessageRequestTimeout = v.MessageRequestTimeout
+	}
+	if v.MultiFileEditStrategy != nil {
+		r.MultiFileEditStrategy = v.MultiFileEditStrategy
+	}
+	if v.MultiFileEditAutoSave != nil {
+		r.MultiFileEditAutoSave = v.MultiFileEditAutoSave
+	}
+	if v.MultiFileEditQuickfix != nil {
+		r.MultiFileEditQuickfix = v.MultiFileEditQuickfix
+	}
+	if v.MultipleLocations != nil {
+		r.MultipleLocations = v.MultipleLocations
+	}
 	if v.Gofumpt != nil {
 		r.Gofumpt = v.Gofumpt
 	}
@@ -76,4 +127,7 @@ func (r *Config) Apply(v *Config) {
 	if v.ExperimentalAllowModfileModifications != nil {
 		r.ExperimentalAllowModfileModifications = v.ExperimentalAllowModfileModifications
 	}
+	if v.ExperimentalPullDiagnostics != nil {
+		r.ExperimentalPullDiagnostics = v.ExperimentalPullDiagnostics
+	}
 }
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/govim/govim"
+	"github.com/govim/govim/cmd/govim/config"
+	"github.com/govim/govim/cmd/govim/internal/golang_org_x_tools_gopls/protocol"
+	"github.com/govim/govim/cmd/govim/internal/types"
+)
+
+// diagnosticSeverities are the valid arguments of CommandDiagnosticNext and
+// CommandDiagnosticPrev, from most to least severe
+var diagnosticSeverities = []config.DiagnosticSeverity{
+	config.DiagnosticSeverityError,
+	config.DiagnosticSeverityWarning,
+	config.DiagnosticSeverityInformation,
+	config.DiagnosticSeverityHint,
+}
+
+func (v *vimstate) diagnosticNext(flags govim.CommandFlags, args ...string) error {
+	return v.moveToDiagnostic(flags, true, args...)
+}
+
+func (v *vimstate) diagnosticPrev(flags govim.CommandFlags, args ...string) error {
+	return v.moveToDiagnostic(flags, false, args...)
+}
+
+func (v *vimstate) diagnosticSeverityComplete(args ...json.RawMessage) (interface{}, error) {
+	lead := v.ParseString(args[0])
+	var results []string
+	for _, s := range diagnosticSeverities {
+		if strings.HasPrefix(string(s), lead) {
+			results = append(results, string(s))
+		}
+	}
+	return results, nil
+}
+
+// diagnosticPos is the position of a diagnostic, used to order diagnostics
+// relative to the cursor in the same way as diagnostics() sorts them
+type diagnosticPos struct {
+	filename string
+	line     int
+	col      int
+}
+
+func (p diagnosticPos) compare(q diagnosticPos) int {
+	if c := strings.Compare(p.filename, q.filename); c != 0 {
+		return c
+	}
+	if p.line != q.line {
+		return p.line - q.line
+	}
+	return p.col - q.col
+}
+
+// moveToDiagnostic moves the cursor to the count-th next (or previous)
+// diagnostic relative to the cursor, wrapping around at the end (or start)
+// of the buffer, or of all files with a bang. Diagnostics less severe than
+// the optional severity argument are skipped.
+func (v *vimstate) moveToDiagnostic(flags govim.CommandFlags, next bool, args ...string) error {
+	min := config.DiagnosticSeverityHint
+	if len(args) == 1 {
+		min = config.DiagnosticSeverity(args[0])
+		if _, ok := severityThresholds[min]; !ok {
+			return fmt.Errorf("invalid severity %q; must be one of %q", args[0], diagnosticSeverities)
+		}
+	}
+	acrossFiles := flags.Bang != nil && *flags.Bang
+
+	cursor, err := v.cursorPos()
+	if err != nil {
+		return fmt.Errorf("failed to get cursor position: %v", err)
+	}
+	// When the cursor is not in a buffer tracked by govim, the zero position
+	// orders before all diagnostics
+	var curr diagnosticPos
+	if cursor.Point != nil {
+		curr = diagnosticPos{
+			filename: cursor.Point.Buffer().URI().Path(),
+			line:     cursor.Point.Line(),
+			col:      cursor.Point.Col(),
+		}
+	} else if !acrossFiles {
+		return fmt.Errorf("cursor is not in a buffer tracked by govim")
+	}
+
+	// Several diagnostics can start at the same position, of which only the
+	// first is a candidate so that each move changes the cursor position.
+	var cands []types.Diagnostic
+	var poss []diagnosticPos
+	for _, d := range *v.diagnostics() {
+		if !meetsSeverity(d.Severity, min) {
+			continue
+		}
+		if !acrossFiles && d.Filename != curr.filename {
+			continue
+		}
+		p := diagnosticPos{filename: d.Filename, line: d.Range.Start.Line(), col: d.Range.Start.Col()}
+		if len(poss) > 0 && poss[len(poss)-1] == p {
+			continue
+		}
+		cands = append(cands, d)
+		poss = append(poss, p)
+	}
+	if len(cands) == 0 {
+		v.ChannelEx(`echom "No diagnostics"`)
+		return nil
+	}
+
+	n := len(cands)
+	count := *flags.Count
+	if count < 1 {
+		// Vim passes a count of 0 through, e.g. for :0GOVIMDiagnosticNext
+		count = 1
+	}
+	var i int
+	if next {
+		// The first diagnostic after the cursor, or else wrap to the first
+		for i = 0; i < n && poss[i].compare(curr) <= 0; i++ {
+		}
+		i = (i + count - 1) % n
+	} else {
+		// The last diagnostic before the cursor, or else wrap to the last
+		for i = n - 1; i >= 0 && poss[i].compare(curr) >= 0; i-- {
+		}
+		if i < 0 {
+			i = n - 1
+		}
+		i = ((i-(count-1))%n + n) % n
+	}
+
+	d := cands[i]
+	loc := protocol.Location{
+		URI: protocol.URIFromPath(d.Filename),
+		Range: protocol.Range{
+			Start: d.Range.Start.ToPosition(),
+			End:   d.Range.End.ToPosition(),
+		},
+	}
+	if err := v.loadLocation(flags.Mods, loc); err != nil {
+		return err
+	}
+	_, err = v.hover()
+	return err
+}
+
+// diagnosticRelated moves the cursor to the count-th location related to the
+// diagnostics under the cursor, numbered as in the hover popup.
+func (v *vimstate) diagnosticRelated(flags govim.CommandFlags, args ...string) error {
+	b, pos, err := v.bufCursorPos()
+	if err != nil {
+		return fmt.Errorf("failed to get current position: %v", err)
+	}
+	var related []protocol.Location
+	for _, d := range v.diagnosticsAt(b, *pos.Point) {
+		for _, r := range d.Related {
+			related = append(related, r.Location)
+		}
+	}
+	if len(related) == 0 {
+		v.ChannelEx(`echom "No related locations"`)
+		return nil
+	}
+	n := *flags.Count
+	if n < 1 || n > len(related) {
+		return fmt.Errorf("no related location %d; there are %d", n, len(related))
+	}
+	return v.loadLocation(flags.Mods, related[n-1])
+}
@@ -1,14 +1,22 @@
 package main
 
 import (
+	"encoding/json"
 	"os"
+	"path"
+	"path/filepath"
 	"sort"
 	"strings"
 
+	"github.com/govim/govim/cmd/govim/config"
 	"github.com/govim/govim/cmd/govim/internal/golang_org_x_tools_gopls/protocol"
 	"github.com/govim/govim/cmd/govim/internal/types"
 )
 
+// diagnosticsChangedEvent is the pattern of the User autocommand fired when
+// diagnostics change
+const diagnosticsChangedEvent = PluginPrefix + "DiagnosticsChanged"
+
 // diagnostics returns the last received LSP diagnostics from gopls
 // and acts as a lazy conversion mechanism. The purpose is to avoid converting
 // lsp diagnostics unless they are needed by govim.
@@ -47,6 +55,9 @@ func (v *vimstate) diagnostics() *[]types.Diagnostic {
 			buf = types.NewBuffer(-1, fn, byts, false)
 		}
 		for _, d := range lspDiags {
+			if !v.showDiagnostic(fn, d.Source, types.Severity(d.Severity)) {
+				continue
+			}
 			s, err := types.VisualPointFromPosition(buf, d.Range.Start)
 			if err != nil {
 				v.Logf("redefineDiagnostics: failed to resolve start position: %v", err)
@@ -57,14 +68,20 @@ func (v *vimstate) diagnostics() *[]types.Diagnostic {
 				v.Logf("redefineDiagnostics: failed to resolve end position: %v", err)
 				continue
 			}
-			diags = append(diags, types.Diagnostic{
+			diag := types.Diagnostic{
 				Filename: fn,
 				Source:   d.Source,
 				Range:    types.Range{Start: s, End: e},
 				Text:     d.Message,
 				Buf:      buf.Num,
 				Severity: types.Severity(d.Severity),
-			})
+				Related:  d.RelatedInformation,
+				Tags:     d.Tags,
+			}
+			if d.CodeDescription != nil {
+				diag.CodeHref = string(d.CodeDescription.Href)
+			}
+			diags = append(diags, diag)
 		}
 	}
 
@@ -89,6 +106,10 @@ func (v *vimstate) handleDiagnosticsChanged() error {
 		return err
 	}
 
+	if err := v.updateLocationListsWithDiagnostics(false); err != nil {
+		return err
+	}
+
 	if err := v.updateSigns(false); err != nil {
 		v.Logf("redefineDiagnostics: failed to place/remove signs: %v", err)
 	}
@@ -96,5 +117,130 @@ func (v *vimstate) handleDiagnosticsChanged() error {
 	if err := v.redefineHighlights(false); err != nil {
 		v.Logf("redefineDiagnostics: failed to apply highlights: %v", err)
 	}
+
+	v.fireDiagnosticsChanged()
 	return nil
 }
+
+// fireDiagnosticsChanged fires the diagnosticsChangedEvent User autocommand
+// if the diagnostics have changed since it was last fired, such that e.g.
+// statusline plugins that use FunctionDiagnosticCounts can redraw.
+func (v *vimstate) fireDiagnosticsChanged() {
+	diags := v.diagnostics()
+	if v.lastDiagnosticsUserEvent == diags {
+		return
+	}
+	v.lastDiagnosticsUserEvent = diags
+	v.ChannelExf("if exists('#User#%[1]v') | doautocmd <nomodeline> User %[1]v | endif", diagnosticsChangedEvent)
+}
+
+// diagnosticCounts is the result of FunctionDiagnosticCounts
+type diagnosticCounts struct {
+	Buffer    map[config.DiagnosticSeverity]int `json:"buffer"`
+	Workspace map[config.DiagnosticSeverity]int `json:"workspace"`
+}
+
+// diagnosticCounts returns the number of diagnostics of each severity in the
+// buffer given by the optional argument, the current buffer by default, and
+// in the workspace. Only the diagnostics that are shown are counted, see
+// config.DiagnosticsMinSeverity.
+func (v *vimstate) diagnosticCounts(args ...json.RawMessage) (interface{}, error) {
+	var bufnr int
+	if len(args) > 0 {
+		bufnr = v.ParseInt(args[0])
+	} else {
+		bufnr = v.ParseInt(v.ChannelExpr(`bufnr("")`))
+	}
+	res := diagnosticCounts{
+		Buffer:    make(map[config.DiagnosticSeverity]int),
+		Workspace: make(map[config.DiagnosticSeverity]int),
+	}
+	for s := range severityThresholds {
+		res.Buffer[s] = 0
+		res.Workspace[s] = 0
+	}
+	for _, d := range *v.diagnostics() {
+		s, ok := severityNames[d.Severity]
+		if !ok {
+			continue
+		}
+		res.Workspace[s]++
+		if d.Buf == bufnr {
+			res.Buffer[s]++
+		}
+	}
+	return res, nil
+}
+
+// severityThresholds maps the minimum severities that can be configured to
+// the least severe of the severities they show
+var severityThresholds = map[config.DiagnosticSeverity]types.Severity{
+	config.DiagnosticSeverityError:       types.SeverityErr,
+	config.DiagnosticSeverityWarning:     types.SeverityWarn,
+	config.DiagnosticSeverityInformation: types.SeverityInfo,
+	config.DiagnosticSeverityHint:        types.SeverityHint,
+}
+
+// severityNames maps severities to their names in config.DiagnosticSeverity
+var severityNames = map[types.Severity]config.DiagnosticSeverity{
+	types.SeverityErr:  config.DiagnosticSeverityError,
+	types.SeverityWarn: config.DiagnosticSeverityWarning,
+	types.SeverityInfo: config.DiagnosticSeverityInformation,
+	types.SeverityHint: config.DiagnosticSeverityHint,
+}
+
+// showDiagnostic returns true if a diagnostic in the file fn from source with
+// severity sev meets all of the configured minimum severities that apply to
+// it, see config.DiagnosticsMinSeverity.
+func (v *vimstate) showDiagnostic(fn, source string, sev types.Severity) bool {
+	if min := v.config.DiagnosticsMinSeverity; min != nil && !meetsSeverity(sev, *min) {
+		return false
+	}
+	if bySource := v.config.DiagnosticsMinSeverityBySource; bySource != nil {
+		for pattern, min := range *bySource {
+			if ok, _ := path.Match(pattern, source); ok && !meetsSeverity(sev, min) {
+				return false
+			}
+		}
+	}
+	if byPath := v.config.DiagnosticsMinSeverityByPath; byPath != nil && len(*byPath) > 0 {
+		rel, err := filepath.Rel(v.workingDirectory, fn)
+		if err != nil {
+			rel = fn
+		}
+		rel = filepath.ToSlash(rel)
+		for pattern, min := range *byPath {
+			if matchDiagnosticPath(pattern, rel) && !meetsSeverity(sev, min) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// meetsSeverity returns true if sev is at least as severe as min
+func meetsSeverity(sev types.Severity, min config.DiagnosticSeverity) bool {
+	if min == config.DiagnosticSeverityNone {
+		return false
+	}
+	t, ok := severityThresholds[min]
+	// Lower values are more severe
+	return !ok || sev <= t
+}
+
+// matchDiagnosticPath returns true if pattern matches the slash-separated
+// path p or one of its parent directories, or if pattern has no slash, the
+// base name of p.
+func matchDiagnosticPath(pattern, p string) bool {
+	if !strings.Contains(pattern, "/") {
+		if ok, _ := path.Match(pattern, path.Base(p)); ok {
+			return true
+		}
+	}
+	for ; p != "." && p != "/"; p = path.Dir(p) {
+		if ok, _ := path.Match(pattern, p); ok {
+			return true
+		}
+	}
+	return false
+}
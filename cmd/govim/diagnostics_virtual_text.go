@@ -0,0 +1,152 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/govim/govim/cmd/govim/config"
+	"github.com/govim/govim/cmd/govim/internal/types"
+)
+
+// diagnosticVirtualTextPropTypes are the text property types used to show the
+// messages of diagnostics as virtual text, by severity. They are distinct from
+// the types used to highlight diagnostics because virtual text properties are
+// removed by type rather than ID.
+var diagnosticVirtualTextPropTypes = map[types.Severity]string{
+	types.SeverityErr:  string(config.HighlightErr) + "VirtualText",
+	types.SeverityWarn: string(config.HighlightWarn) + "VirtualText",
+	types.SeverityInfo: string(config.HighlightInfo) + "VirtualText",
+	types.SeverityHint: string(config.HighlightHint) + "VirtualText",
+}
+
+// diagnosticsVirtualTextLine is a line of a buffer
+type diagnosticsVirtualTextLine struct {
+	bufnr int
+	line  int
+}
+
+// diagnosticsVirtualTextMode returns the configured DiagnosticsVirtualText,
+// or DiagnosticsVirtualTextOff if Vim does not support virtual text after the
+// end of a line.
+func (v *vimstate) diagnosticsVirtualTextMode() config.DiagnosticsVirtualText {
+	if !v.hasVirtualTextAfter || v.config.DiagnosticsVirtualText == nil {
+		return config.DiagnosticsVirtualTextOff
+	}
+	return *v.config.DiagnosticsVirtualText
+}
+
+// redefineDiagnosticsVirtualText replaces the virtual text of diagnostics in
+// all loaded buffers with the messages of diags, or of those on the cursor
+// line in DiagnosticsVirtualTextCursorLine mode.
+func (v *vimstate) redefineDiagnosticsVirtualText(diags []types.Diagnostic) {
+	mode := v.diagnosticsVirtualTextMode()
+	maxWidth := 0
+	if v.config.DiagnosticsVirtualTextMaxWidth != nil {
+		maxWidth = *v.config.DiagnosticsVirtualTextMaxWidth
+	}
+
+	v.BatchStart()
+	defer v.BatchCancelIfNotEnded()
+	v.batchRemoveDiagnosticsVirtualText()
+
+	// The most severe diagnostic of each line is shown. Diagnostics are sorted
+	// by position, hence so are the lines.
+	var lines []diagnosticsVirtualTextLine
+	shown := make(map[diagnosticsVirtualTextLine]types.Diagnostic)
+	count := make(map[diagnosticsVirtualTextLine]int)
+	for _, d := range diags {
+		if d.Buf < 0 {
+			continue
+		}
+		if buf, ok := v.buffers[d.Buf]; ok && !buf.Loaded {
+			continue
+		}
+		l := diagnosticsVirtualTextLine{bufnr: d.Buf, line: d.Range.Start.Line()}
+		if mode == config.DiagnosticsVirtualTextCursorLine && l != v.diagnosticsVirtualTextCursor {
+			continue
+		}
+		if s, ok := shown[l]; !ok {
+			lines = append(lines, l)
+			shown[l] = d
+		} else if d.Severity < s.Severity {
+			// Lower values are more severe
+			shown[l] = d
+		}
+		count[l]++
+	}
+	for _, l := range lines {
+		d := shown[l]
+		typ, ok := diagnosticVirtualTextPropTypes[d.Severity]
+		if !ok {
+			typ = diagnosticVirtualTextPropTypes[types.SeverityErr]
+		}
+		v.BatchAssertChannelCall(assertPropAdd, "prop_add", l.line, 0, propAddTextDict{
+			Type:            typ,
+			Text:            diagnosticVirtualText(d.Text, count[l]-1, maxWidth),
+			TextAlign:       "after",
+			TextPaddingLeft: 2,
+			TextWrap:        "truncate",
+			BufNr:           l.bufnr,
+		})
+	}
+	v.MustBatchEnd()
+}
+
+// setDiagnosticsVirtualTextCursor records the cursor line used in
+// DiagnosticsVirtualTextCursorLine mode, and updates the virtual text of
+// diagnostics if the line has changed.
+func (v *vimstate) setDiagnosticsVirtualTextCursor(pos types.CursorPosition) {
+	var l diagnosticsVirtualTextLine
+	if pos.Point != nil {
+		l = diagnosticsVirtualTextLine{bufnr: pos.BufNr, line: pos.Line()}
+	}
+	if l == v.diagnosticsVirtualTextCursor {
+		return
+	}
+	v.diagnosticsVirtualTextCursor = l
+	if v.diagnosticsVirtualTextMode() != config.DiagnosticsVirtualTextCursorLine {
+		return
+	}
+	v.redefineDiagnosticsVirtualText(*v.diagnostics())
+}
+
+// removeDiagnosticsVirtualText removes the virtual text of diagnostics from
+// all loaded buffers
+func (v *vimstate) removeDiagnosticsVirtualText() {
+	v.BatchStart()
+	defer v.BatchCancelIfNotEnded()
+	v.batchRemoveDiagnosticsVirtualText()
+	v.MustBatchEnd()
+}
+
+func (v *vimstate) batchRemoveDiagnosticsVirtualText() {
+	for bufnr, b := range v.buffers {
+		if !b.Loaded {
+			continue // vim removes properties when a buffer is unloaded
+		}
+		for _, typ := range diagnosticVirtualTextPropTypes {
+			v.BatchChannelCall("prop_remove", struct {
+				Type  string `json:"type"`
+				BufNr int    `json:"bufnr"`
+				All   int    `json:"all"`
+			}{typ, bufnr, 1})
+		}
+	}
+}
+
+// diagnosticVirtualText returns the virtual text for the diagnostic message
+// msg on a line with others more diagnostics. Only the first line of msg is
+// used, truncated to maxWidth characters unless maxWidth is 0, so that the
+// count of other diagnostics is always shown.
+func diagnosticVirtualText(msg string, others, maxWidth int) string {
+	if i := strings.IndexByte(msg, '\n'); i >= 0 {
+		msg = msg[:i]
+	}
+	if r := []rune(msg); maxWidth > 0 && len(r) > maxWidth {
+		msg = string(r[:maxWidth-1]) + "…"
+	}
+	if others > 0 {
+		msg += fmt.Sprintf(" (+%d more)", others)
+	}
+	return msg
+}
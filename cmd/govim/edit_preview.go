@@ -0,0 +1,133 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/govim/govim"
+	"github.com/govim/govim/cmd/govim/internal/golang_org_x_tools/diff"
+	"github.com/govim/govim/cmd/govim/internal/golang_org_x_tools_gopls/protocol"
+)
+
+// documentContents returns the contents of the document uri, from its buffer
+// if it is loaded or else from disk.
+func (v *vimstate) documentContents(uri protocol.DocumentURI) ([]byte, error) {
+	for _, b := range v.buffers {
+		if b.Loaded && b.URI() == uri {
+			return b.Contents(), nil
+		}
+	}
+	byts, err := os.ReadFile(uri.Path())
+	if err != nil {
+		return nil, fmt.Errorf("failed to read contents of %v: %v", uri.Path(), err)
+	}
+	return byts, nil
+}
+
+// workspaceEditDiff returns a unified diff of the text edits of changes
+// against the current contents of the documents they change, ordered by file
+// name, followed by the files that changes create, rename and delete. Paths
+// are relative to the working directory.
+func (v *vimstate) workspaceEditDiff(changes []protocol.DocumentChange) (string, error) {
+	edits := make(map[protocol.DocumentURI][]protocol.TextEdit)
+	var uris []string
+	var resourceOps []string
+	for _, c := range changes {
+		switch {
+		case c.CreateFile != nil:
+			resourceOps = append(resourceOps, "new file "+v.diffPath(c.CreateFile.URI))
+			continue
+		case c.RenameFile != nil:
+			resourceOps = append(resourceOps, "rename from "+v.diffPath(c.RenameFile.OldURI), "rename to "+v.diffPath(c.RenameFile.NewURI))
+			continue
+		case c.DeleteFile != nil:
+			resourceOps = append(resourceOps, "deleted file "+v.diffPath(c.DeleteFile.URI))
+			continue
+		}
+		uri := c.TextDocumentEdit.TextDocument.URI
+		if _, ok := edits[uri]; !ok {
+			uris = append(uris, string(uri))
+		}
+		edits[uri] = append(edits[uri], protocol.AsTextEdits(c.TextDocumentEdit.Edits)...)
+	}
+	sort.Strings(uris)
+
+	var sb strings.Builder
+	for _, u := range uris {
+		uri := protocol.DocumentURI(u)
+		before, err := v.documentContents(uri)
+		if err != nil {
+			return "", err
+		}
+		_, dedits, err := protocol.ApplyEdits(protocol.NewMapper(uri, before), edits[uri])
+		if err != nil {
+			return "", fmt.Errorf("failed to apply edits to %v: %v", uri.Path(), err)
+		}
+		fn := v.diffPath(uri)
+		u, err := diff.ToUnified("a/"+fn, "b/"+fn, string(before), dedits, 3)
+		if err != nil {
+			return "", fmt.Errorf("failed to compute diff of %v: %v", fn, err)
+		}
+		sb.WriteString(u)
+	}
+	for _, op := range resourceOps {
+		sb.WriteString(op + "\n")
+	}
+	return sb.String(), nil
+}
+
+// diffPath returns the path of the document uri relative to the working
+// directory, with forward slashes.
+func (v *vimstate) diffPath(uri protocol.DocumentURI) string {
+	fn := uri.Path()
+	if rel, err := filepath.Rel(v.workingDirectory, fn); err == nil {
+		fn = rel
+	}
+	return filepath.ToSlash(fn)
+}
+
+// openDiffPreview opens a diff in a new scratch buffer, in a split according
+// to mods, and returns the number of the buffer. kind is used in the name of
+// the buffer.
+func (v *vimstate) openDiffPreview(mods govim.CommModList, kind, d string) int {
+	v.lastDiffPreviewID++
+	bufName := fmt.Sprintf("govim-%s-%d.diff", kind, v.lastDiffPreviewID)
+	bufNr := v.ParseInt(v.ChannelCall("bufadd", bufName))
+	v.ChannelExf("silent call bufload(%d)", bufNr)
+	v.BatchStart()
+	v.BatchChannelCall("setbufvar", bufNr, "&buftype", "nofile")
+	v.BatchChannelCall("setbufvar", bufNr, "&bufhidden", "wipe")
+	v.BatchChannelCall("setbufvar", bufNr, "&swapfile", 0)
+	v.BatchChannelCall("setbufvar", bufNr, "&buflisted", 0)
+	v.BatchChannelCall("setbufline", bufNr, 1, strings.Split(strings.TrimSuffix(d, "\n"), "\n"))
+	v.BatchChannelCall("setbufvar", bufNr, "&modifiable", 0)
+	v.MustBatchEnd()
+	// kind may come from user input, e.g. the analyzer of CommandFixAll
+	v.ChannelExf("%v split %s", mods, v.ParseString(v.ChannelCall("fnameescape", bufName)))
+	v.ChannelEx("setlocal filetype=diff")
+	return bufNr
+}
+
+// confirmEditPreview previews changes as a diff and asks the user, with
+// prompt, whether to apply them. The preview is closed once the user has
+// answered.
+func (v *vimstate) confirmEditPreview(mods govim.CommModList, kind, prompt string, changes []protocol.DocumentChange) (bool, error) {
+	d, err := v.workspaceEditDiff(changes)
+	if err != nil {
+		return false, err
+	}
+	vp := v.Viewport()
+	bufNr := v.openDiffPreview(mods, kind, d)
+	v.ChannelEx("redraw")
+	answer := v.ParseString(v.ChannelCall("input", prompt+" [y/N] "))
+	v.ChannelExf("bwipeout %d", bufNr)
+	v.ChannelCall("win_gotoid", vp.Current.WinID)
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true, nil
+	}
+	return false, nil
+}
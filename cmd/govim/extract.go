@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"go/scanner"
+	"go/token"
+	gotypes "go/types"
+
+	"github.com/govim/govim"
+	"github.com/govim/govim/cmd/govim/internal/golang_org_x_tools_gopls/protocol"
+	"github.com/govim/govim/cmd/govim/internal/types"
+)
+
+// extractKinds are the code action kinds, and titles for versions of gopls
+// that only use the refactor.extract kind, of the extractions performed by
+// the extract commands.
+var extractKinds = map[string][]struct {
+	kind  protocol.CodeActionKind
+	title string
+}{
+	"function": {{"refactor.extract.function", "Extract function"}},
+	"method":   {{"refactor.extract.method", "Extract method"}},
+	"variable": {
+		{"refactor.extract.variable", "Extract variable"},
+		// gopls extracts constant expressions as constants
+		{"refactor.extract.constant", "Extract constant"},
+	},
+}
+
+func (v *vimstate) extractFunction(flags govim.CommandFlags, args ...string) error {
+	return v.extract(flags, "function", args...)
+}
+
+func (v *vimstate) extractMethod(flags govim.CommandFlags, args ...string) error {
+	return v.extract(flags, "method", args...)
+}
+
+func (v *vimstate) extractVariable(flags govim.CommandFlags, args ...string) error {
+	return v.extract(flags, "variable", args...)
+}
+
+// extract extracts the range to a new function, method or variable, and then
+// renames the new identifier. The optional argument is the new name, without
+// which the user is prompted as for CommandRename.
+func (v *vimstate) extract(flags govim.CommandFlags, what string, args ...string) error {
+	b, _, err := v.bufCursorPos()
+	if err != nil {
+		return fmt.Errorf("failed to get cursor position: %v", err)
+	}
+	start, end, err := v.rangeFromFlags(b, flags)
+	if err != nil {
+		return err
+	}
+	actions, err := v.server.CodeAction(context.Background(), &protocol.CodeActionParams{
+		TextDocument: b.ToTextDocumentIdentifier(),
+		Range: protocol.Range{
+			Start: start.ToPosition(),
+			End:   end.ToPosition(),
+		},
+		Context: protocol.CodeActionContext{
+			Only: []protocol.CodeActionKind{protocol.RefactorExtract},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("codeAction failed: %v", err)
+	}
+	var action *protocol.CodeAction
+Actions:
+	for i, ca := range actions {
+		for _, k := range extractKinds[what] {
+			if ca.Kind == k.kind || (ca.Kind == protocol.RefactorExtract && ca.Title == k.title) {
+				action = &actions[i]
+				break Actions
+			}
+		}
+	}
+	if action == nil || action.Disabled != nil {
+		return fmt.Errorf("cannot extract %s from the selected range", what)
+	}
+	if codeActionNeedsResolve(*action) {
+		if action, err = v.resolveCodeAction(*action); err != nil {
+			return err
+		}
+	}
+
+	before := goIdents(b.Contents())
+	switch {
+	case action.Edit != nil:
+		res, err := v.applyWorkspaceEdit(&protocol.ApplyWorkspaceEditParams{Edit: *action.Edit})
+		if err != nil {
+			return err
+		}
+		if !res.Applied {
+			return fmt.Errorf("failed to apply extraction: %v", res.FailureReason)
+		}
+	case action.Command != nil:
+		if err := v.executeCommand(action.Command, nil); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("code action %q has neither an edit nor a command", action.Title)
+	}
+
+	// The new identifier is not part of the response, so find the first
+	// identifier introduced by the extraction at or after the range.
+	pos, ok := newIdentPos(before, b.Contents(), start.Offset())
+	if !ok {
+		return fmt.Errorf("failed to find the identifier introduced by the extraction")
+	}
+	p, err := types.PointFromOffset(b, pos)
+	if err != nil {
+		return fmt.Errorf("failed to derive point from offset %d: %v", pos, err)
+	}
+	v.ChannelCall("cursor", p.Line(), p.Col())
+	return v.rename(flags, args...)
+}
+
+// goIdents returns the occurrences of the identifiers in the Go source src,
+// excluding keywords and predeclared identifiers, as a map from name to
+// offsets.
+func goIdents(src []byte) map[string][]int {
+	res := make(map[string][]int)
+	fset := token.NewFileSet()
+	f := fset.AddFile("", -1, len(src))
+	var s scanner.Scanner
+	s.Init(f, src, nil, 0)
+	for {
+		pos, tok, lit := s.Scan()
+		if tok == token.EOF {
+			return res
+		}
+		if tok != token.IDENT || lit == "_" || gotypes.Universe.Lookup(lit) != nil {
+			continue
+		}
+		res[lit] = append(res[lit], f.Offset(pos))
+	}
+}
+
+// newIdentPos returns the offset of the first occurrence, at or after offset
+// from, of an identifier in after that does not occur in before. If there is
+// no such occurrence after from, the first occurrence anywhere is returned.
+func newIdentPos(before map[string][]int, after []byte, from int) (int, bool) {
+	first, firstAfter := -1, -1
+	for name, offsets := range goIdents(after) {
+		if _, ok := before[name]; ok {
+			continue
+		}
+		for _, o := range offsets {
+			if first == -1 || o < first {
+				first = o
+			}
+			if o >= from && (firstAfter == -1 || o < firstAfter) {
+				firstAfter = o
+			}
+		}
+	}
+	if firstAfter != -1 {
+		return firstAfter, true
+	}
+	return first, first != -1
+}
@@ -0,0 +1,247 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/govim/govim"
+	"github.com/govim/govim/cmd/govim/internal/golang_org_x_tools_gopls/protocol"
+)
+
+// fixAll applies the suggested fixes of all diagnostics reported by the
+// analyzer given as argument, across all files with diagnostics. With a bang
+// the fixes are not applied; instead a diff of them is previewed.
+func (v *vimstate) fixAll(flags govim.CommandFlags, args ...string) error {
+	analyzer := args[0]
+	dryRun := flags.Bang != nil && *flags.Bang
+
+	diags := make(map[protocol.DocumentURI][]protocol.Diagnostic)
+	var uris []string
+	v.diagnosticsChangedLock.Lock()
+	for uri, p := range v.rawDiagnostics {
+		for _, d := range p.Diagnostics {
+			if d.Source != analyzer {
+				continue
+			}
+			if _, ok := diags[uri]; !ok {
+				uris = append(uris, string(uri))
+			}
+			diags[uri] = append(diags[uri], d)
+		}
+	}
+	v.diagnosticsChangedLock.Unlock()
+	if len(uris) == 0 {
+		v.ChannelExf("echom %q", fmt.Sprintf("No diagnostics from %v", analyzer))
+		return nil
+	}
+	// So that conflicting fixes are resolved reproducibly
+	sort.Strings(uris)
+
+	edits := newFixAllEdits()
+	var fixed, skipped int
+	for _, u := range uris {
+		uri := protocol.DocumentURI(u)
+		for _, d := range diags[uri] {
+			fix, ok, err := v.diagnosticFix(uri, d)
+			if err != nil {
+				return err
+			}
+			if !ok || !edits.add(fix.edit.DocumentChanges) {
+				skipped++
+				continue
+			}
+			fixed++
+		}
+	}
+	if fixed == 0 {
+		v.ChannelExf("echom %q", fmt.Sprintf("No fixes to apply for %v (%d skipped)", analyzer, skipped))
+		return nil
+	}
+
+	changes := edits.documentChanges()
+	var files []string
+	for _, c := range changes {
+		fn := c.TextDocumentEdit.TextDocument.URI.Path()
+		if rel, err := filepath.Rel(v.workingDirectory, fn); err == nil {
+			fn = rel
+		}
+		files = append(files, fn)
+	}
+	summary := fmt.Sprintf("%d fixes for %v in %d files: %v", fixed, analyzer, len(files), strings.Join(files, ", "))
+	if skipped > 0 {
+		summary += fmt.Sprintf(" (%d skipped)", skipped)
+	}
+
+	if dryRun {
+		d, err := v.workspaceEditDiff(changes)
+		if err != nil {
+			return err
+		}
+		v.openDiffPreview(flags.Mods, "fixall-"+analyzer, d)
+		v.ChannelExf("echom %q", "Preview of "+summary)
+		return nil
+	}
+	if err := v.applyMultiBufTextedits(flags.Mods, changes); err != nil {
+		return err
+	}
+	v.ChannelExf("echom %q", "Applied "+summary)
+	return nil
+}
+
+// diagnosticFix returns the preferred quick fix of d in the document uri,
+// with its edit resolved. ok is false if there is no fix that consists of
+// text edits, e.g. only fixes that are commands.
+func (v *vimstate) diagnosticFix(uri protocol.DocumentURI, d protocol.Diagnostic) (fix suggestedFix, ok bool, err error) {
+	params := &protocol.CodeActionParams{
+		TextDocument: protocol.TextDocumentIdentifier{URI: uri},
+		Range:        d.Range,
+		Context: protocol.CodeActionContext{
+			Diagnostics: []protocol.Diagnostic{d},
+			Only:        []protocol.CodeActionKind{protocol.QuickFix},
+		},
+	}
+	codeActions, err := v.server.CodeAction(context.Background(), params)
+	if err != nil {
+		return fix, false, fmt.Errorf("codeAction failed: %v", err)
+	}
+	var candidates []protocol.CodeAction
+	for _, ca := range codeActions {
+		if ca.Kind != protocol.QuickFix {
+			continue
+		}
+		for _, cd := range ca.Diagnostics {
+			if cd.Message == d.Message && cd.Range == d.Range {
+				candidates = append(candidates, ca)
+				break
+			}
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].IsPreferred && !candidates[j].IsPreferred
+	})
+	for _, ca := range candidates {
+		fix = newSuggestedFix(ca)
+		if err := fix.resolve(v); err != nil {
+			return fix, false, err
+		}
+		if len(fix.edit.DocumentChanges) > 0 {
+			return fix, true, nil
+		}
+	}
+	return fix, false, nil
+}
+
+// fixAllEdits are the combined text edits of several fixes, by document
+type fixAllEdits struct {
+	uris     []protocol.DocumentURI
+	versions map[protocol.DocumentURI]int32
+	edits    map[protocol.DocumentURI][]protocol.TextEdit
+}
+
+func newFixAllEdits() *fixAllEdits {
+	return &fixAllEdits{
+		versions: make(map[protocol.DocumentURI]int32),
+		edits:    make(map[protocol.DocumentURI][]protocol.TextEdit),
+	}
+}
+
+// add adds the text edits of a fix, unless any of them conflicts with an
+// edit already added, in which case add returns false. Edits identical to
+// those already added, e.g. of an import needed by several fixes, are
+// dropped.
+func (f *fixAllEdits) add(changes []protocol.DocumentChange) bool {
+	added := make(map[protocol.DocumentURI][]protocol.TextEdit)
+	for _, c := range changes {
+		if c.TextDocumentEdit == nil {
+			return false
+		}
+		uri := c.TextDocumentEdit.TextDocument.URI
+	Edits:
+		for _, e := range protocol.AsTextEdits(c.TextDocumentEdit.Edits) {
+			for _, prev := range append(f.edits[uri], added[uri]...) {
+				if prev == e {
+					continue Edits
+				}
+				if textEditsConflict(prev, e) {
+					return false
+				}
+			}
+			added[uri] = append(added[uri], e)
+		}
+	}
+	for _, c := range changes {
+		uri := c.TextDocumentEdit.TextDocument.URI
+		if _, ok := f.versions[uri]; !ok {
+			f.uris = append(f.uris, uri)
+			f.versions[uri] = c.TextDocumentEdit.TextDocument.Version
+		}
+	}
+	for uri, edits := range added {
+		f.edits[uri] = append(f.edits[uri], edits...)
+	}
+	return true
+}
+
+// documentChanges returns the combined edits, ordered by document
+func (f *fixAllEdits) documentChanges() []protocol.DocumentChange {
+	uris := append([]protocol.DocumentURI(nil), f.uris...)
+	sort.Slice(uris, func(i, j int) bool {
+		return uris[i] < uris[j]
+	})
+	var res []protocol.DocumentChange
+	for _, uri := range uris {
+		edits := f.edits[uri]
+		if len(edits) == 0 {
+			continue
+		}
+		var elems []protocol.Or_TextDocumentEdit_edits_Elem
+		for _, e := range edits {
+			elems = append(elems, protocol.Or_TextDocumentEdit_edits_Elem{Value: e})
+		}
+		res = append(res, protocol.DocumentChange{
+			TextDocumentEdit: &protocol.TextDocumentEdit{
+				TextDocument: protocol.OptionalVersionedTextDocumentIdentifier{
+					Version:                f.versions[uri],
+					TextDocumentIdentifier: protocol.TextDocumentIdentifier{URI: uri},
+				},
+				Edits: elems,
+			},
+		})
+	}
+	return res
+}
+
+// textEditsConflict returns true if a and b overlap, or are both insertions
+// at the same position such that the order in which they apply matters.
+func textEditsConflict(a, b protocol.TextEdit) bool {
+	if a.Range.Start == a.Range.End && b.Range.Start == b.Range.End {
+		return a.Range.Start == b.Range.Start
+	}
+	return protocol.ComparePosition(a.Range.Start, b.Range.End) < 0 &&
+		protocol.ComparePosition(b.Range.Start, a.Range.End) < 0
+}
+
+// fixAllComplete completes the argument of CommandFixAll with the sources of
+// the current diagnostics, e.g. the names of analyzers.
+func (v *vimstate) fixAllComplete(args ...json.RawMessage) (interface{}, error) {
+	lead := v.ParseString(args[0])
+	seen := make(map[string]bool)
+	var results []string
+	v.diagnosticsChangedLock.Lock()
+	for _, p := range v.rawDiagnostics {
+		for _, d := range p.Diagnostics {
+			if d.Source == "" || seen[d.Source] || !strings.HasPrefix(d.Source, lead) {
+				continue
+			}
+			seen[d.Source] = true
+			results = append(results, d.Source)
+		}
+	}
+	v.diagnosticsChangedLock.Unlock()
+	sort.Strings(results)
+	return results, nil
+}
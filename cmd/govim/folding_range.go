@@ -0,0 +1,194 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/govim/govim"
+	"github.com/govim/govim/cmd/govim/internal/golang_org_x_tools_gopls/protocol"
+	"github.com/govim/govim/cmd/govim/internal/types"
+)
+
+// foldingRangesState is the folding state of a buffer. The fold level of each
+// line is held by the buffer variable govim_fold_levels, which is read by
+// GOVIMFoldExpr().
+type foldingRangesState struct {
+	// version is the version of the buffer the fold levels were computed for,
+	// or 0 if they have yet to be computed
+	version int32
+
+	// cancel cancels the ongoing gopls.FoldingRange request, if any
+	cancel context.CancelFunc
+}
+
+func (v *vimstate) foldingRangesEnabled() bool {
+	return v.config.FoldingRanges != nil && *v.config.FoldingRanges
+}
+
+// updateAllFoldingRanges updates the fold levels of all loaded buffers
+func (v *vimstate) updateAllFoldingRanges() error {
+	for _, b := range v.buffers {
+		v.updateFoldingRanges(b)
+	}
+	return nil
+}
+
+// refreshFoldingRanges recomputes the fold levels of all loaded buffers,
+// regardless of whether the buffers have changed.
+func (v *vimstate) refreshFoldingRanges() error {
+	for _, s := range v.foldingRanges {
+		s.version = 0
+	}
+	return v.updateAllFoldingRanges()
+}
+
+// updateFoldingRanges requests the folding ranges of b in the background,
+// unless the fold levels of the current version of b are already known. Any
+// ongoing request for b is cancelled so that only the latest response is
+// used.
+func (v *vimstate) updateFoldingRanges(b *types.Buffer) {
+	if !v.foldingRangesEnabled() || !b.Loaded || !strings.HasSuffix(b.Name, ".go") {
+		return
+	}
+	s, ok := v.foldingRanges[b.Num]
+	if !ok {
+		s = &foldingRangesState{}
+		v.foldingRanges[b.Num] = s
+	}
+	if s.cancel != nil {
+		s.cancel()
+		s.cancel = nil
+	}
+	if s.version == b.Version {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+	version := b.Version
+	v.tomb.Go(func() error {
+		ranges, err := v.server.FoldingRange(ctx, &protocol.FoldingRangeParams{
+			TextDocument: b.ToTextDocumentIdentifier(),
+		})
+		select {
+		case <-ctx.Done():
+			return nil
+		default:
+		}
+		v.govimplugin.Schedule(func(govim.Govim) error {
+			// The buffer has changed again, or has been unloaded
+			select {
+			case <-ctx.Done():
+				return nil
+			default:
+			}
+			s.cancel = nil
+			if err != nil {
+				return fmt.Errorf("failed to call gopls.FoldingRange: %v", err)
+			}
+			if cb, ok := v.buffers[b.Num]; !ok || cb != b || !b.Loaded || b.Version != version {
+				return nil
+			}
+			s.version = version
+			v.setFoldLevels(b.Num, foldLevels(ranges, bytes.Count(b.Contents(), []byte("\n"))))
+			return nil
+		})
+		return nil
+	})
+}
+
+// resetFoldingRanges cancels any ongoing request for the buffer bufnr and
+// forgets its fold levels, e.g. because the buffer has been unloaded.
+func (v *vimstate) resetFoldingRanges(bufnr int) {
+	s, ok := v.foldingRanges[bufnr]
+	if !ok {
+		return
+	}
+	if s.cancel != nil {
+		s.cancel()
+	}
+	delete(v.foldingRanges, bufnr)
+}
+
+// removeFoldingRanges removes the fold levels of all loaded buffers, such that
+// GOVIMFoldExpr() no longer folds any line.
+func (v *vimstate) removeFoldingRanges() {
+	for bufnr := range v.foldingRanges {
+		v.resetFoldingRanges(bufnr)
+		if b, ok := v.buffers[bufnr]; ok && b.Loaded {
+			v.setFoldLevels(bufnr, []string{})
+		}
+	}
+}
+
+// setFoldLevels sets the fold levels of the buffer bufnr, and updates the
+// folds of the windows showing the buffer with foldmethod=expr.
+func (v *vimstate) setFoldLevels(bufnr int, levels []string) {
+	var wins []int
+	v.BatchStart()
+	defer v.BatchCancelIfNotEnded()
+	v.BatchChannelCall("setbufvar", bufnr, "govim_fold_levels", levels)
+	v.BatchChannelCall("win_findbuf", bufnr)
+	res := v.MustBatchEnd()
+	v.Parse(res[1], &wins)
+
+	// Setting foldexpr makes Vim re-evaluate it for every line
+	v.BatchStart()
+	for _, w := range wins {
+		v.BatchChannelCall("win_execute", w, "if &l:foldmethod ==# 'expr' | let &l:foldexpr = &l:foldexpr | endif")
+	}
+	v.MustBatchEnd()
+}
+
+// foldLevels returns the foldexpr value of each of the n lines of a buffer
+// folded according to ranges. A line that starts one or more folds is ">N",
+// where N is the level of the innermost fold it starts, and a line that ends
+// one or more folds is "<N", where N is the level of the outermost fold it
+// ends.
+func foldLevels(ranges []protocol.FoldingRange, n int) []string {
+	var folds []protocol.FoldingRange
+	seen := make(map[[2]uint32]bool)
+	for _, r := range ranges {
+		key := [2]uint32{r.StartLine, r.EndLine}
+		// There is nothing to fold within a single line
+		if r.EndLine <= r.StartLine || int(r.EndLine) >= n || seen[key] {
+			continue
+		}
+		seen[key] = true
+		folds = append(folds, r)
+	}
+	depth := make([]int, n)
+	starts := make(map[uint32]int)
+	ends := make(map[uint32]int)
+	for _, f := range folds {
+		for l := f.StartLine; l <= f.EndLine; l++ {
+			depth[l]++
+		}
+		// The level of a fold is the number of folds that contain it,
+		// including itself
+		level := 0
+		for _, g := range folds {
+			if g.StartLine <= f.StartLine && g.EndLine >= f.EndLine {
+				level++
+			}
+		}
+		if level > starts[f.StartLine] {
+			starts[f.StartLine] = level
+		}
+		if e, ok := ends[f.EndLine]; !ok || level < e {
+			ends[f.EndLine] = level
+		}
+	}
+	levels := make([]string, n)
+	for l, d := range depth {
+		if s, ok := starts[uint32(l)]; ok {
+			levels[l] = fmt.Sprintf(">%d", s)
+		} else if e, ok := ends[uint32(l)]; ok {
+			levels[l] = fmt.Sprintf("<%d", e)
+		} else {
+			levels[l] = fmt.Sprint(d)
+		}
+	}
+	return levels
+}
@@ -94,8 +94,19 @@ func (v *vimstate) formatBufferRange(b *types.Buffer, mode config.FormatOnSave,
 		switch len(organizeImports) {
 		case 0:
 		case 1:
+			action := organizeImports[0]
+			if codeActionNeedsResolve(action) {
+				res, err := v.resolveCodeAction(action)
+				if err != nil {
+					return err
+				}
+				action = *res
+			}
+			if action.Edit == nil {
+				return fmt.Errorf("missing edit for organizeImports")
+			}
 			// there should just be a single file
-			dcs := organizeImports[0].Edit.DocumentChanges
+			dcs := action.Edit.DocumentChanges
 			switch len(dcs) {
 			case 1:
 				dc := dcs[0]
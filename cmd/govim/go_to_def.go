@@ -2,9 +2,13 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
 
 	"github.com/govim/govim"
+	"github.com/govim/govim/cmd/govim/config"
+	"github.com/govim/govim/cmd/govim/internal/golang_org_x_tools/jsonrpc2"
 	"github.com/govim/govim/cmd/govim/internal/golang_org_x_tools_gopls/protocol"
 	"github.com/govim/govim/cmd/govim/internal/types"
 	"github.com/kr/pretty"
@@ -25,11 +29,7 @@ func (v *vimstate) gotoDef(flags govim.CommandFlags, args ...string) error {
 	if err != nil {
 		return fmt.Errorf("failed to call gopls.Definition: %v\nparams were: %v", err, pretty.Sprint(params))
 	}
-	loc, err := v.handleProtocolLocations(cb, pos, locs)
-	if err != nil || loc == nil {
-		return err
-	}
-	return v.loadLocation(flags.Mods, *loc, args...)
+	return v.gotoLocations(flags.Mods, "definition", locs, args...)
 }
 
 func (v *vimstate) gotoTypeDef(flags govim.CommandFlags, args ...string) error {
@@ -47,47 +47,78 @@ func (v *vimstate) gotoTypeDef(flags govim.CommandFlags, args ...string) error {
 	if err != nil {
 		return fmt.Errorf("failed to call gopls.TypeDefinition: %v\nparams were: %v", err, pretty.Sprint(params))
 	}
-	loc, err := v.handleProtocolLocations(cb, pos, locs)
-	if err != nil || loc == nil {
-		return err
-	}
-	return v.loadLocation(flags.Mods, *loc, args...)
+	return v.gotoLocations(flags.Mods, "type definition", locs, args...)
 }
 
-func (v *vimstate) handleProtocolLocations(b *types.Buffer, pos types.CursorPosition, locs []protocol.Location) (*protocol.Location, error) {
-	switch len(locs) {
-	case 0:
-		v.ChannelEx(`echorerr "No definition exists under cursor"`)
-		return nil, nil
-	case 1:
-	default:
-		return nil, fmt.Errorf("got multiple locations (%v); don't know how to handle this", len(locs))
+func (v *vimstate) gotoDecl(flags govim.CommandFlags, args ...string) error {
+	cb, pos, err := v.bufCursorPos()
+	if err != nil {
+		return fmt.Errorf("failed to determine cursor position: %v", err)
 	}
-
-	loc := locs[0]
-	v.jumpStack = append(v.jumpStack[:v.jumpStackPos], protocol.Location{
-		URI: protocol.DocumentURI(b.URI()),
-		Range: protocol.Range{
-			Start: pos.ToPosition(),
-			End:   pos.ToPosition(),
+	params := &protocol.DeclarationParams{
+		TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+			TextDocument: cb.ToTextDocumentIdentifier(),
+			Position:     pos.ToPosition(),
 		},
-	})
-	v.jumpStackPos++
-	return &loc, nil
+	}
+	res, err := v.server.Declaration(context.Background(), params)
+	if isMethodNotFound(err) {
+		// In Go the declaration of an identifier is its definition
+		return v.gotoDef(flags, args...)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to call gopls.Declaration: %v\nparams were: %v", err, pretty.Sprint(params))
+	}
+	var locs []protocol.Location
+	if res != nil {
+		switch r := res.Value.(type) {
+		case protocol.Declaration:
+			locs = r
+		case []protocol.DeclarationLink:
+			for _, l := range r {
+				locs = append(locs, protocol.Location{URI: l.TargetURI, Range: l.TargetSelectionRange})
+			}
+		}
+	}
+	return v.gotoLocations(flags.Mods, "declaration", locs, args...)
 }
 
-func (v *vimstate) gotoPrevDef(flags govim.CommandFlags, args ...string) error {
-	if v.jumpStackPos == 0 {
-		v.ChannelEx(`echom "Already at top of stack"`)
+// isMethodNotFound returns true if err is the error returned by gopls for a
+// method it does not implement.
+func isMethodNotFound(err error) bool {
+	var werr *jsonrpc2.WireError
+	return errors.As(err, &werr) && werr.Code == jsonrpc2.ErrMethodNotFound.(*jsonrpc2.WireError).Code
+}
+
+// gotoLocations jumps to the location in locs, the result of looking up what
+// (e.g. "definition") at the cursor. Where there are several locations they
+// are presented according to the MultipleLocations config. mods and args are
+// those of the command, as used by loadLocation.
+func (v *vimstate) gotoLocations(mods govim.CommModList, what string, locs []protocol.Location, args ...string) error {
+	// The name of the symbol in the jump stack
+	name := v.ParseString(v.ChannelExpr(`expand("<cword>")`))
+	switch len(locs) {
+	case 0:
+		v.ChannelExf("echom %q", fmt.Sprintf("No %v exists under cursor", what))
 		return nil
+	case 1:
+		return v.jumpToLocation(mods, name, locs[0], args...)
 	}
-	v.jumpStackPos -= *flags.Count
-	if v.jumpStackPos < 0 {
-		v.jumpStackPos = 0
+	var entries []quickfixEntry
+	for _, l := range locs {
+		qf, err := v.locationToQuickfix(l, true)
+		if err != nil {
+			return fmt.Errorf("failed to convert location to quickfix entry: %v", err)
+		}
+		entries = append(entries, qf)
 	}
-	loc := v.jumpStack[v.jumpStackPos]
-
-	return v.loadLocation(flags.Mods, loc, args...)
+	if v.config.MultipleLocations != nil && *v.config.MultipleLocations == config.MultipleLocationsQuickfix {
+		// A new list, rather than replacing the current one
+		v.ChannelCall("setqflist", []quickfixEntry{}, " ", qflistProps{Title: "govim: " + what + "s", Items: entries})
+		v.ChannelExf("echom %q", fmt.Sprintf("%v %vs; see the quickfix list", len(locs), what))
+		return v.jumpToLocation(mods, name, locs[0], args...)
+	}
+	return v.openLocationsPicker(mods, name, what, locs, entries, args...)
 }
 
 // args is expected to be the command args for either gotodef or gotoprevdef
@@ -175,7 +206,14 @@ MovedToTargetWin:
 	}
 	nb, ok := v.buffers[bn]
 	if !ok {
-		return fmt.Errorf("should have resolved a buffer; we didn't")
+		if strings.HasSuffix(tf, ".go") {
+			return fmt.Errorf("should have resolved a buffer; we didn't")
+		}
+		// The buffer is not tracked by govim, e.g. a document gopls asked to
+		// be shown, so assume the position is in terms of bytes.
+		v.ChannelCall("cursor", loc.Range.Start.Line+1, loc.Range.Start.Character+1)
+		v.ChannelEx("normal! zz")
+		return nil
 	}
 	newPos, err := types.PointFromPosition(nb, loc.Range.Start)
 	if err != nil {
@@ -164,7 +164,70 @@ func (g *govimplugin) startGopls() error {
 	initParams.Capabilities.Workspace.DidChangeConfiguration.DynamicRegistration = true
 	initParams.Capabilities.Workspace.DidChangeWatchedFiles.DynamicRegistration = true
 
+	initParams.Capabilities.Workspace.InlayHint = &protocol.InlayHintWorkspaceClientCapabilities{
+		RefreshSupport: true,
+	}
+	initParams.Capabilities.Workspace.CodeLens = &protocol.CodeLensWorkspaceClientCapabilities{
+		RefreshSupport: true,
+	}
+	// Vim folds whole lines
+	initParams.Capabilities.TextDocument.FoldingRange = &protocol.FoldingRangeClientCapabilities{
+		LineFoldingOnly: true,
+	}
+	initParams.Capabilities.Workspace.FoldingRange = &protocol.FoldingRangeWorkspaceClientCapabilities{
+		RefreshSupport: true,
+	}
+	// Related information, documentation links and tags are shown in the
+	// hover popup and highlighted, whether diagnostics are published or pulled
+	diagnosticsCapabilities := protocol.DiagnosticsCapabilities{
+		RelatedInformation: true,
+		TagSupport: &protocol.ClientDiagnosticsTagOptions{
+			ValueSet: []protocol.DiagnosticTag{protocol.Unnecessary, protocol.Deprecated},
+		},
+		CodeDescriptionSupport: true,
+	}
+	initParams.Capabilities.TextDocument.PublishDiagnostics.DiagnosticsCapabilities = diagnosticsCapabilities
+	initParams.Capabilities.TextDocument.Diagnostic = &protocol.DiagnosticClientCapabilities{
+		RelatedDocumentSupport:  true,
+		DiagnosticsCapabilities: diagnosticsCapabilities,
+	}
+	initParams.Capabilities.Workspace.Diagnostics = &protocol.DiagnosticWorkspaceClientCapabilities{
+		RefreshSupport: true,
+	}
+	initParams.Capabilities.TextDocument.CodeAction = protocol.CodeActionClientCapabilities{
+		DataSupport: true,
+		ResolveSupport: &protocol.ClientCodeActionResolveOptions{
+			Properties: []string{"edit"},
+		},
+	}
+	initParams.Capabilities.TextDocument.Rename = &protocol.RenameClientCapabilities{
+		PrepareSupport: true,
+	}
+	initParams.Capabilities.TextDocument.DocumentSymbol = protocol.DocumentSymbolClientCapabilities{
+		HierarchicalDocumentSymbolSupport: true,
+	}
+	initParams.Capabilities.TextDocument.SemanticTokens = semanticTokensClientCapabilities()
+	initParams.Capabilities.Workspace.SemanticTokens = &protocol.SemanticTokensWorkspaceClientCapabilities{
+		RefreshSupport: true,
+	}
+
+	// Files are created, renamed and deleted as part of workspace edits, e.g.
+	// when renaming a package
+	initParams.Capabilities.Workspace.WorkspaceEdit = &protocol.WorkspaceEditClientCapabilities{
+		DocumentChanges:    true,
+		ResourceOperations: []protocol.ResourceOperationKind{protocol.Create, protocol.Rename, protocol.Delete},
+	}
+	initParams.Capabilities.Workspace.FileOperations = &protocol.FileOperationClientCapabilities{
+		DidCreate:  true,
+		DidRename:  true,
+		WillRename: true,
+		DidDelete:  true,
+	}
+
 	initParams.Capabilities.Window.WorkDoneProgress = true
+	initParams.Capabilities.Window.ShowDocument = &protocol.ShowDocumentClientCapabilities{
+		Support: true,
+	}
 
 	initParams.ClientInfo = &protocol.ClientInfo{
 		Name: "govim",
@@ -198,11 +261,25 @@ func (g *govimplugin) startGopls() error {
 		goplsConfig["allowModfileModifications"] = *conf.ExperimentalAllowModfileModifications
 	}
 
+	if conf.ExperimentalPullDiagnostics != nil {
+		goplsConfig[goplsPullDiagnostics] = *conf.ExperimentalPullDiagnostics
+	}
+
 	initParams.InitializationOptions = goplsConfig
 
-	if _, err := g.server.Initialize(context.Background(), initParams); err != nil {
+	initRes, err := g.server.Initialize(context.Background(), initParams)
+	if err != nil {
 		return fmt.Errorf("failed to initialise gopls: %v", err)
 	}
+	if err := g.vimstate.setSemanticTokensProvider(initRes.Capabilities.SemanticTokensProvider); err != nil {
+		return err
+	}
+	if err := g.vimstate.setDiagnosticProvider(initRes.Capabilities.DiagnosticProvider); err != nil {
+		return err
+	}
+	if ws := initRes.Capabilities.Workspace; ws != nil {
+		g.vimstate.fileOperations = ws.FileOperations
+	}
 
 	if err := g.server.Initialized(context.Background(), &protocol.InitializedParams{}); err != nil {
 		return fmt.Errorf("failed to call gopls.Initialized: %v", err)
@@ -4,8 +4,10 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"os/exec"
 	"reflect"
 	"strings"
+	"time"
 	"unicode"
 
 	"github.com/govim/govim"
@@ -28,19 +30,53 @@ const (
 	goplsVerboseOutput        = "verboseOutput"
 	goplsEnv                  = "env"
 	goplsAnalyses             = "analyses"
+	goplsHints                = "hints"
+	goplsSemanticTokens       = "semanticTokens"
 	goplsCodeLenses           = "codelenses"
 	goplsSymbolMatcher        = "symbolMatcher"
 	goplsSymbolStyle          = "symbolStyle"
 	goplsGofumpt              = "gofumpt"
 	goplsDirectoryFilters     = "directoryFilters"
 	goplsMemoryMode           = "memoryMode"
+	goplsPullDiagnostics      = "pullDiagnostics"
 )
 
 var _ protocol.Client = (*govimplugin)(nil)
 
-func (g *govimplugin) ShowDocument(context.Context, *protocol.ShowDocumentParams) (*protocol.ShowDocumentResult, error) {
+func (g *govimplugin) ShowDocument(ctxt context.Context, params *protocol.ShowDocumentParams) (*protocol.ShowDocumentResult, error) {
 	defer absorbShutdownErr()
-	panic("ShowDocument not implemented yet")
+	g.logGoplsClientf("ShowDocument callback: %v", pretty.Sprint(params))
+
+	// gopls can ask for a document to be shown whilst executing a command,
+	// during which the Vim thread is blocked (see executeCommand). Hence the
+	// document is shown asynchronously, and assumed to be shown successfully.
+	if params.External || !strings.HasPrefix(string(params.URI), "file://") {
+		g.vimstate.configLock.Lock()
+		var opener string
+		if o := g.vimstate.config.OpenExternalWith; o != nil {
+			opener = *o
+		}
+		g.vimstate.configLock.Unlock()
+
+		if args := strings.Fields(opener); len(args) > 0 {
+			cmd := exec.Command(args[0], append(args[1:], string(params.URI))...)
+			if err := cmd.Start(); err != nil {
+				g.logGoplsClientf("failed to run %q: %v", opener, err)
+				return &protocol.ShowDocumentResult{Success: false}, nil
+			}
+			go cmd.Wait()
+			return &protocol.ShowDocumentResult{Success: true}, nil
+		}
+		g.Schedule(func(govim.Govim) error {
+			g.vimstate.copyURL(string(params.URI))
+			return nil
+		})
+		return &protocol.ShowDocumentResult{Success: true}, nil
+	}
+	g.Schedule(func(govim.Govim) error {
+		return g.vimstate.showDocument(params)
+	})
+	return &protocol.ShowDocumentResult{Success: true}, nil
 }
 
 func (g *govimplugin) ShowMessage(ctxt context.Context, params *protocol.ShowMessageParams) error {
@@ -74,9 +110,64 @@ func (g *govimplugin) ShowMessage(ctxt context.Context, params *protocol.ShowMes
 	return nil
 }
 
-func (g *govimplugin) ShowMessageRequest(context.Context, *protocol.ShowMessageRequestParams) (*protocol.MessageActionItem, error) {
+func (g *govimplugin) ShowMessageRequest(ctxt context.Context, params *protocol.ShowMessageRequestParams) (*protocol.MessageActionItem, error) {
 	defer absorbShutdownErr()
-	panic("ShowMessageRequest not implemented yet")
+	g.logGoplsClientf("ShowMessageRequest callback: %v", pretty.Sprint(params))
+
+	g.vimstate.configLock.Lock()
+	var timeout time.Duration
+	if t := g.vimstate.config.MessageRequestTimeout; t != nil && *t != "" {
+		var err error
+		if timeout, err = time.ParseDuration(*t); err != nil {
+			g.vimstate.configLock.Unlock()
+			return nil, fmt.Errorf("failed to parse MessageRequestTimeout %q: %v", *t, err)
+		}
+	}
+	g.vimstate.configLock.Unlock()
+
+	// The user's choice is awaited here, rather than on the Vim thread, such
+	// that Vim remains responsive whilst the prompt is shown
+	req := &messageRequest{
+		params: params,
+		res:    make(chan *protocol.MessageActionItem, 1),
+	}
+	g.applyEditsLock.Lock()
+	if g.messageRequestsCh == nil {
+		g.applyEditsLock.Unlock()
+		g.Schedule(func(govim.Govim) error {
+			return g.vimstate.showMessageRequest(req)
+		})
+	} else {
+		// There is an ongoing blocking call on the vim thread, so a Schedule
+		// here would only show the prompt once the call has returned. Pass the
+		// prompt to the vim thread instead.
+		g.messageRequestsCh <- req
+		g.applyEditsLock.Unlock()
+	}
+	var expired <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		expired = timer.C
+	}
+	var res *protocol.MessageActionItem
+	select {
+	case res = <-req.res:
+	case <-expired:
+		g.Schedule(func(govim.Govim) error {
+			g.vimstate.closeMessageRequest(req)
+			return nil
+		})
+	case <-ctxt.Done():
+		g.Schedule(func(govim.Govim) error {
+			g.vimstate.closeMessageRequest(req)
+			return nil
+		})
+		return nil, ctxt.Err()
+	case <-g.inShutdown:
+	}
+	g.logGoplsClientf("ShowMessageRequest response: %v", pretty.Sprint(res))
+	return res, nil
 }
 
 func (g *govimplugin) LogMessage(ctxt context.Context, params *protocol.LogMessageParams) error {
@@ -87,7 +178,11 @@ func (g *govimplugin) LogMessage(ctxt context.Context, params *protocol.LogMessa
 
 func (g *govimplugin) FoldingRangeRefresh(context.Context) error {
 	defer absorbShutdownErr()
-	panic("FoldingRangeRefresh not implemented yet")
+	g.logGoplsClientf("FoldingRangeRefresh callback")
+	g.Schedule(func(govim.Govim) error {
+		return g.vimstate.refreshFoldingRanges()
+	})
+	return nil
 }
 
 func (g *govimplugin) Telemetry(context.Context, interface{}) error {
@@ -106,7 +201,16 @@ func (g *govimplugin) RegisterCapability(ctxt context.Context, params *protocol.
 		case "workspace/didChangeWatchedFiles":
 			// For now ignore per github.com/govim/govim/issues/950
 		case "textDocument/semanticTokens":
-			// For now ignore per github.com/govim/govim/issues/1083
+			// gopls registers semantic tokens when they are enabled in the config,
+			// at which point the legend is known and tokens can be requested.
+			opts := r.RegisterOptions
+			g.Schedule(func(govim.Govim) error {
+				v := g.vimstate
+				if err := v.setSemanticTokensProvider(opts); err != nil {
+					return err
+				}
+				return v.updateAllSemanticTokens()
+			})
 		default:
 			panic(fmt.Errorf("RegisterCapability called with unknown method: %v", r.Method))
 		}
@@ -125,6 +229,9 @@ func (g *govimplugin) UnregisterCapability(ctxt context.Context, params *protoco
 			// For now ignore per #172
 		case "workspace/didChangeWatchedFiles":
 		// 	// For now ignore per #950
+		case "textDocument/semanticTokens":
+			// Nothing to do: semantic tokens are only requested when enabled
+			// in the config
 		default:
 			panic(fmt.Errorf("UnregisterCapability called with unknown method: %v", pretty.Sprint(params)))
 		}
@@ -184,9 +291,21 @@ func (g *govimplugin) Configuration(ctxt context.Context, params *protocol.Param
 	if conf.Analyses != nil {
 		goplsConfig[goplsAnalyses] = *conf.Analyses
 	}
-	goplsConfig[goplsCodeLenses] = map[string]bool{
+	if conf.InlayHints != nil {
+		goplsConfig[goplsHints] = *conf.InlayHints
+	}
+	if conf.HighlightSemanticTokens != nil {
+		goplsConfig[goplsSemanticTokens] = *conf.HighlightSemanticTokens
+	}
+	codeLenses := map[string]bool{
 		string(settings.CodeLensGCDetails): true, // gc_details
 	}
+	if conf.CodeLenses != nil {
+		for k, v := range *conf.CodeLenses {
+			codeLenses[k] = v
+		}
+	}
+	goplsConfig[goplsCodeLenses] = codeLenses
 	if conf.GoplsEnv != nil {
 		// It is safe not to copy the map here because a new config setting from
 		// Vim creates a new map.
@@ -243,6 +362,11 @@ func (g *govimplugin) PublishDiagnostics(ctxt context.Context, params *protocol.
 	defer absorbShutdownErr()
 	g.logGoplsClientf("PublishDiagnostics callback: %v", pretty.Sprint(params))
 	g.diagnosticsChangedLock.Lock()
+	if g.pullDiagnostics != nil {
+		// Diagnostics are pulled instead, see pullBufferDiagnostics
+		g.diagnosticsChangedLock.Unlock()
+		return nil
+	}
 	uri := params.URI
 	curr, ok := g.rawDiagnostics[uri]
 	g.rawDiagnostics[uri] = params
@@ -333,7 +457,11 @@ func (g *govimplugin) WorkDoneProgressCreate(ctxt context.Context, params *proto
 
 func (g *govimplugin) CodeLensRefresh(context.Context) error {
 	defer absorbShutdownErr()
-	panic("CodeLensRefresh not implemented yet")
+	g.logGoplsClientf("CodeLensRefresh callback")
+	g.Schedule(func(govim.Govim) error {
+		return g.vimstate.updateAllCodeLenses()
+	})
+	return nil
 }
 
 func (g *govimplugin) LogTrace(context.Context, *protocol.LogTraceParams) error {
@@ -343,12 +471,20 @@ func (g *govimplugin) LogTrace(context.Context, *protocol.LogTraceParams) error
 
 func (g *govimplugin) DiagnosticRefresh(context.Context) error {
 	defer absorbShutdownErr()
-	panic("DiagnosticRefresh not implemented yet")
+	g.logGoplsClientf("DiagnosticRefresh callback")
+	g.Schedule(func(govim.Govim) error {
+		return g.vimstate.refreshPullDiagnostics()
+	})
+	return nil
 }
 
 func (g *govimplugin) InlayHintRefresh(context.Context) error {
 	defer absorbShutdownErr()
-	panic("InlayHintRefresh not implemented yet")
+	g.logGoplsClientf("InlayHintRefresh callback")
+	g.Schedule(func(govim.Govim) error {
+		return g.vimstate.updateInlayHints()
+	})
+	return nil
 }
 
 func (g *govimplugin) InlineValueRefresh(context.Context) error {
@@ -358,7 +494,11 @@ func (g *govimplugin) InlineValueRefresh(context.Context) error {
 
 func (g *govimplugin) SemanticTokensRefresh(context.Context) error {
 	defer absorbShutdownErr()
-	panic("SemanticTokensRefresh not implemented yet")
+	g.logGoplsClientf("SemanticTokensRefresh callback")
+	g.Schedule(func(govim.Govim) error {
+		return g.vimstate.updateAllSemanticTokens()
+	})
+	return nil
 }
 
 func (g *govimplugin) TextDocumentContentRefresh(context.Context, *protocol.TextDocumentContentRefreshParams) error {
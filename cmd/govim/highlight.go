@@ -28,6 +28,25 @@ type propAddDict struct {
 	BufNr   int    `json:"bufnr"`
 }
 
+// propAddTextDict is the representation of arguments used in vim's prop_add()
+// when adding a virtual text property. Such properties are assigned a
+// negative ID by vim, so they are removed by type rather than ID.
+type propAddTextDict struct {
+	Type            string `json:"type"`
+	Text            string `json:"text"`
+	TextAlign       string `json:"text_align,omitempty"`
+	TextPaddingLeft int    `json:"text_padding_left,omitempty"`
+	TextWrap        string `json:"text_wrap,omitempty"`
+	BufNr           int    `json:"bufnr"`
+}
+
+// diagnosticTagHighlights are the highlights of the text properties added to
+// diagnostics with tags, in addition to the highlight of their severity
+var diagnosticTagHighlights = map[protocol.DiagnosticTag]config.Highlight{
+	protocol.Unnecessary: config.HighlightUnnecessary,
+	protocol.Deprecated:  config.HighlightDeprecated,
+}
+
 // assertPropAdd is used when we add text properties that might fail due to the fact
 // that the buffer might have changed since the text properties was calculated.
 // There are two vim errors that we like to suppress, invalid line and invalid column.
@@ -47,6 +66,11 @@ func (v *vimstate) textpropDefine() error {
 			Priority:  types.SeverityPriority[s],
 		})
 
+		v.BatchChannelCall("prop_type_add", diagnosticVirtualTextPropTypes[s], propDict{
+			Highlight: string(hi),
+			Priority:  types.SeverityPriority[s],
+		})
+
 		hi = types.SeverityHoverHighlight[s]
 		v.BatchChannelCall("prop_type_add", hi, propDict{
 			Highlight: string(hi),
@@ -61,12 +85,51 @@ func (v *vimstate) textpropDefine() error {
 		Priority:  types.SeverityPriority[types.SeverityErr] + 1,
 	})
 
+	for _, hi := range diagnosticTagHighlights {
+		v.BatchChannelCall("prop_type_add", hi, propDict{
+			Highlight: string(hi),
+			Combine:   true,
+			Priority:  types.SeverityPriority[types.SeverityErr] + 1,
+		})
+	}
+
 	v.BatchChannelCall("prop_type_add", config.HighlightReferences, propDict{
 		Highlight: string(config.HighlightReferences),
 		Combine:   true,
 		Priority:  types.SeverityPriority[types.SeverityErr] + 1,
 	})
 
+	// Semantic token types replace any syntax highlight, whereas modifiers are
+	// combined with the highlight of the token type.
+	for _, t := range semanticTokenTypes {
+		hi := semanticTypeHighlight(string(t.name))
+		v.BatchChannelCall("prop_type_add", hi, propDict{
+			Highlight: string(hi),
+			Priority:  1,
+		})
+	}
+	for _, m := range semanticTokenModifiers {
+		hi := semanticModifierHighlight(string(m.name))
+		v.BatchChannelCall("prop_type_add", hi, propDict{
+			Highlight: string(hi),
+			Combine:   true,
+			Priority:  2,
+		})
+	}
+
+	v.BatchChannelCall("prop_type_add", config.HighlightOutlineCurrent, propDict{
+		Highlight: string(config.HighlightOutlineCurrent),
+		Combine:   true,
+	})
+
+	v.BatchChannelCall("prop_type_add", config.HighlightInlayHint, propDict{
+		Highlight: string(config.HighlightInlayHint),
+	})
+
+	v.BatchChannelCall("prop_type_add", config.HighlightCodeLens, propDict{
+		Highlight: string(config.HighlightCodeLens),
+	})
+
 	v.BatchChannelCall("prop_type_add", config.HighlightSignature, propDict{
 		Highlight: string(config.HighlightSignature),
 		Combine:   true,
@@ -79,6 +142,9 @@ func (v *vimstate) textpropDefine() error {
 		Priority:  types.SeverityPriority[types.SeverityErr] + 1,
 	})
 
+	// Jump stack locations are marked by text properties without a highlight
+	v.BatchChannelCall("prop_type_add", jumpStackPropType, struct{}{})
+
 	res := v.MustBatchEnd()
 	for i := range res {
 		if v.ParseInt(res[i]) != 0 {
@@ -89,7 +155,9 @@ func (v *vimstate) textpropDefine() error {
 }
 
 func (v *vimstate) redefineHighlights(force bool) error {
-	if v.config.HighlightDiagnostics == nil || !*v.config.HighlightDiagnostics {
+	highlight := v.config.HighlightDiagnostics != nil && *v.config.HighlightDiagnostics
+	virtualText := v.diagnosticsVirtualTextMode() != config.DiagnosticsVirtualTextOff
+	if !highlight && !virtualText {
 		return nil
 	}
 	diagsRef := v.diagnostics()
@@ -100,6 +168,13 @@ func (v *vimstate) redefineHighlights(force bool) error {
 	}
 	diags := *diagsRef
 
+	if virtualText {
+		v.redefineDiagnosticsVirtualText(diags)
+	}
+	if !highlight {
+		return nil
+	}
+
 	v.removeTextProps(types.DiagnosticTextPropID)
 
 	v.BatchStart()
@@ -126,6 +201,15 @@ func (v *vimstate) redefineHighlights(force bool) error {
 			d.Range.Start.Col(),
 			propAddDict{string(hi), types.DiagnosticTextPropID, d.Range.End.Line(), d.Range.End.Col(), d.Buf},
 		)
+		for _, t := range d.Tags {
+			if thi, ok := diagnosticTagHighlights[t]; ok {
+				v.BatchAssertChannelCall(assertPropAdd, "prop_add",
+					d.Range.Start.Line(),
+					d.Range.Start.Col(),
+					propAddDict{string(thi), types.DiagnosticTextPropID, d.Range.End.Line(), d.Range.End.Col(), d.Buf},
+				)
+			}
+		}
 	}
 
 	v.MustBatchEnd()
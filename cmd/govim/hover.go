@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"math"
+	"path/filepath"
 	"strings"
 
 	"github.com/govim/govim/cmd/govim/config"
@@ -45,6 +46,17 @@ func (v *vimstate) hoverMsgAt(pos types.Point, tdi protocol.TextDocumentIdentifi
 	return strings.TrimSpace(hovRes.Contents.Value), nil
 }
 
+// diagnosticsAt returns the diagnostics of b whose range contains pos
+func (v *vimstate) diagnosticsAt(b *types.Buffer, pos types.Point) []types.Diagnostic {
+	var res []types.Diagnostic
+	for _, d := range *v.diagnostics() {
+		if b.Num == d.Buf && pos.IsWithin(d.Range) {
+			res = append(res, d)
+		}
+	}
+	return res
+}
+
 func (v *vimstate) showHover(posExpr string, opts map[string]interface{}, userOpts *map[string]interface{}) (interface{}, error) {
 	if v.popupWinID > 0 {
 		v.ChannelCall("popup_close", v.popupWinID)
@@ -88,12 +100,23 @@ func (v *vimstate) showHover(posExpr string, opts map[string]interface{}, userOp
 		}
 	}
 
+	// formatPopupDetail formats a line of details of a diagnostic, indented
+	// below its message, where the prefix uses the "source highlight".
+	formatPopupDetail := func(prefix, text string) types.PopupLine {
+		return types.PopupLine{
+			Text: fmt.Sprintf("  %s %s", prefix, text),
+			Props: []types.PopupProp{
+				{Type: string(config.HighlightHoverDiagSrc), Col: 3, Len: len(prefix)},
+			},
+		}
+	}
+
 	var lines []types.PopupLine
 	if *v.config.HoverDiagnostics {
-		for _, d := range *v.diagnostics() {
-			if (b.Num != d.Buf) || !pos.IsWithin(d.Range) {
-				continue
-			}
+		// Related locations are numbered across all diagnostics, as expected
+		// by CommandDiagnosticRelated
+		related := 0
+		for _, d := range v.diagnosticsAt(b, pos) {
 			for i, l := range strings.Split(d.Text, "\n") {
 				if i == 0 {
 					lines = append(lines, formatPopupline(l, d.Source, d.Severity))
@@ -101,6 +124,18 @@ func (v *vimstate) showHover(posExpr string, opts map[string]interface{}, userOp
 				}
 				lines = append(lines, formatPopupline(l, "", d.Severity))
 			}
+			if d.CodeHref != "" {
+				lines = append(lines, formatPopupDetail("docs:", d.CodeHref))
+			}
+			for _, r := range d.Related {
+				related++
+				fn := r.Location.URI.Path()
+				if rel, err := filepath.Rel(v.workingDirectory, fn); err == nil {
+					fn = rel
+				}
+				prefix := fmt.Sprintf("[%d] %s:%d:", related, fn, r.Location.Range.Start.Line+1)
+				lines = append(lines, formatPopupDetail(prefix, r.Message))
+			}
 		}
 	}
 	msg, err := v.hoverMsgAt(pos, b.ToTextDocumentIdentifier())
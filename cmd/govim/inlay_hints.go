@@ -0,0 +1,187 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/govim/govim"
+	"github.com/govim/govim/cmd/govim/config"
+	"github.com/govim/govim/cmd/govim/internal/golang_org_x_tools_gopls/protocol"
+	"github.com/govim/govim/cmd/govim/internal/types"
+)
+
+// inlayHintsRequest is a request for the inlay hints of the visible lines of
+// a buffer at a specific version.
+type inlayHintsRequest struct {
+	buf     *types.Buffer
+	version int32
+	rng     protocol.Range
+	hints   []protocol.InlayHint
+}
+
+// inlayHintsEnabled returns true if at least one kind of inlay hint is
+// enabled, and Vim supports virtual text.
+func (v *vimstate) inlayHintsEnabled() bool {
+	if !v.hasVirtualText || v.config.InlayHints == nil {
+		return false
+	}
+	for _, on := range *v.config.InlayHints {
+		if on {
+			return true
+		}
+	}
+	return false
+}
+
+// winScrolled updates the inlay hints for the lines that have been scrolled
+// into view.
+func (v *vimstate) winScrolled(args ...json.RawMessage) error {
+	return v.updateInlayHints()
+}
+
+// updateInlayHints requests inlay hints for the lines of Go buffers that are
+// visible in the current tab page. The hints are requested in the background
+// and rendered once all responses have arrived. Any ongoing request is
+// cancelled so that only the latest response is rendered.
+func (v *vimstate) updateInlayHints() error {
+	if v.cancelInlayHints != nil {
+		v.cancelInlayHints()
+		v.cancelInlayHints = nil
+	}
+	if !v.inlayHintsEnabled() {
+		return nil
+	}
+
+	vp := v.Viewport()
+	reqs := make(map[int]*inlayHintsRequest)
+	for _, w := range vp.Windows {
+		b, ok := v.buffers[w.BufNr]
+		if !ok || !b.Loaded {
+			continue
+		}
+		// The range covers complete lines, i.e. up to the start of the line
+		// after the bottom line.
+		start := protocol.Position{Line: uint32(w.TopLine - 1)}
+		end := protocol.Position{Line: uint32(w.BotLine)}
+		r, ok := reqs[b.Num]
+		if !ok {
+			reqs[b.Num] = &inlayHintsRequest{
+				buf:     b,
+				version: b.Version,
+				rng:     protocol.Range{Start: start, End: end},
+			}
+			continue
+		}
+		// The same buffer is visible in more than one window, so request hints
+		// for the union of the visible lines.
+		if start.Line < r.rng.Start.Line {
+			r.rng.Start = start
+		}
+		if end.Line > r.rng.End.Line {
+			r.rng.End = end
+		}
+	}
+	if len(reqs) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	v.cancelInlayHints = cancel
+	v.tomb.Go(func() error {
+		for _, r := range reqs {
+			hints, err := v.server.InlayHint(ctx, &protocol.InlayHintParams{
+				TextDocument: r.buf.ToTextDocumentIdentifier(),
+				Range:        r.rng,
+			})
+			select {
+			case <-ctx.Done():
+				return nil
+			default:
+			}
+			if err != nil {
+				v.Logf("inlayHint call failed for %v: %v", r.buf.Name, err)
+				continue
+			}
+			r.hints = hints
+		}
+		v.govimplugin.Schedule(func(govim.Govim) error {
+			// A newer request has or will soon be sent, so this response is no
+			// longer relevant
+			select {
+			case <-ctx.Done():
+				return nil
+			default:
+			}
+			v.renderInlayHints(reqs)
+			return nil
+		})
+		return nil
+	})
+	return nil
+}
+
+// renderInlayHints replaces the inlay hints of each requested buffer with the
+// hints in the response. Buffers that have changed since the request was made
+// are skipped; the change itself triggers a new request.
+func (v *vimstate) renderInlayHints(reqs map[int]*inlayHintsRequest) {
+	v.BatchStart()
+	defer v.BatchCancelIfNotEnded()
+	for bufnr, r := range reqs {
+		if b, ok := v.buffers[bufnr]; !ok || b != r.buf || !b.Loaded || b.Version != r.version {
+			continue
+		}
+		v.batchRemoveInlayHints(bufnr)
+		for _, h := range r.hints {
+			p, err := types.PointFromPosition(r.buf, h.Position)
+			if err != nil {
+				v.Logf("failed to convert inlay hint position %v to point: %v", h.Position, err)
+				continue
+			}
+			v.BatchAssertChannelCall(assertPropAdd, "prop_add", p.Line(), p.Col(), propAddTextDict{
+				Type:  string(config.HighlightInlayHint),
+				Text:  inlayHintText(h),
+				BufNr: bufnr,
+			})
+		}
+	}
+	v.MustBatchEnd()
+}
+
+// removeInlayHints removes the inlay hints from all loaded buffers
+func (v *vimstate) removeInlayHints() {
+	if v.cancelInlayHints != nil {
+		v.cancelInlayHints()
+		v.cancelInlayHints = nil
+	}
+	v.BatchStart()
+	defer v.BatchCancelIfNotEnded()
+	for bufnr, b := range v.buffers {
+		if b.Loaded {
+			v.batchRemoveInlayHints(bufnr)
+		}
+	}
+	v.MustBatchEnd()
+}
+
+func (v *vimstate) batchRemoveInlayHints(bufnr int) {
+	v.BatchChannelCall("prop_remove", struct {
+		Type  string `json:"type"`
+		BufNr int    `json:"bufnr"`
+		All   int    `json:"all"`
+	}{string(config.HighlightInlayHint), bufnr, 1})
+}
+
+func inlayHintText(h protocol.InlayHint) string {
+	var sb strings.Builder
+	if h.PaddingLeft {
+		sb.WriteString(" ")
+	}
+	for _, l := range h.Label {
+		sb.WriteString(l.Value)
+	}
+	if h.PaddingRight {
+		sb.WriteString(" ")
+	}
+	return sb.String()
+}
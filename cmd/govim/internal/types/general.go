@@ -45,6 +45,17 @@ type Diagnostic struct {
 	Text     string
 	Buf      int
 	Severity Severity
+
+	// CodeHref is a link to the documentation of the diagnostic, e.g. of the
+	// analyzer that reported it
+	CodeHref string
+
+	// Related are other locations related to the diagnostic, e.g. those of
+	// conflicting declarations
+	Related []protocol.DiagnosticRelatedInformation
+
+	// Tags mark diagnostics of unnecessary or deprecated code
+	Tags []protocol.DiagnosticTag
 }
 
 // Severity is the govim internal representation of the LSP DiagnosticSeverites
@@ -86,6 +97,11 @@ var SeverityHoverHighlight = map[Severity]config.Highlight{
 type TextPropID int
 
 const (
-	DiagnosticTextPropID = 0
-	ReferencesTextPropID = 1
+	DiagnosticTextPropID     = 0
+	ReferencesTextPropID     = 1
+	SemanticTokensTextPropID = 2
+
+	// JumpStackTextPropIDBase is the lowest of the IDs of the text properties
+	// that mark jump stack locations, each of which has its own ID
+	JumpStackTextPropIDBase = 1000
 )
@@ -25,6 +25,7 @@ type ProgressInitiator string
 
 const (
 	GoTest                 ProgressInitiator = "GoTest"
+	CodeLens               ProgressInitiator = "CodeLens"
 	WorkDoneProgressCreate ProgressInitiator = "WorkDoneProgressCreate"
 )
 
@@ -9,10 +9,17 @@ import (
 type VimConfig struct {
 	FormatOnSave                                 *config.FormatOnSave
 	QuickfixAutoDiagnostics                      *int
+	LocationListDiagnostics                      *int
 	QuickfixSigns                                *int
 	HighlightDiagnostics                         *int
+	DiagnosticsVirtualText                       *config.DiagnosticsVirtualText
+	DiagnosticsVirtualTextMaxWidth               *int
 	HighlightReferences                          *int
+	HighlightSemanticTokens                      *int
 	HoverDiagnostics                             *int
+	DiagnosticsMinSeverity                       *config.DiagnosticSeverity
+	DiagnosticsMinSeverityBySource               *map[string]config.DiagnosticSeverity
+	DiagnosticsMinSeverityByPath                 *map[string]config.DiagnosticSeverity
 	CompletionDeepCompletions                    *int
 	CompletionMatcher                            *config.CompletionMatcher
 	SymbolMatcher                                *config.SymbolMatcher
@@ -24,7 +31,17 @@ type VimConfig struct {
 	GoplsEnv                                     *map[string]string
 	GoplsDirectoryFilters                        *[]string
 	Analyses                                     *map[string]int
+	InlayHints                                   *map[string]int
+	CodeLenses                                   *map[string]int
+	ShowCodeLenses                               *int
+	FoldingRanges                                *int
 	OpenLastProgressWith                         *string
+	OpenExternalWith                             *string
+	MessageRequestTimeout                        *string
+	MultiFileEditStrategy                        *config.MultiFileEditStrategy
+	MultiFileEditAutoSave                        *int
+	MultiFileEditQuickfix                        *int
+	MultipleLocations                            *config.MultipleLocations
 	Gofumpt                                      *int
 	ExperimentalAutoreadLoadedBuffers            *int
 	ExperimentalMouseTriggeredHoverPopupOptions  *map[string]interface{}
@@ -32,6 +49,7 @@ type VimConfig struct {
 	ExperimentalWorkaroundCompleteoptLongest     *int
 	ExperimentalProgressPopups                   *int
 	ExperimentalAllowModfileModifications        *int
+	ExperimentalPullDiagnostics                  *int
 }
 
 func (c *VimConfig) ToConfig(d config.Config) config.Config {
@@ -39,9 +57,16 @@ func (c *VimConfig) ToConfig(d config.Config) config.Config {
 		FormatOnSave:                      c.FormatOnSave,
 		QuickfixSigns:                     boolVal(c.QuickfixSigns, d.QuickfixSigns),
 		QuickfixAutoDiagnostics:           boolVal(c.QuickfixAutoDiagnostics, d.QuickfixAutoDiagnostics),
+		LocationListDiagnostics:           boolVal(c.LocationListDiagnostics, d.LocationListDiagnostics),
 		HighlightDiagnostics:              boolVal(c.HighlightDiagnostics, d.HighlightDiagnostics),
+		DiagnosticsVirtualText:            c.DiagnosticsVirtualText,
+		DiagnosticsVirtualTextMaxWidth:    intVal(c.DiagnosticsVirtualTextMaxWidth, d.DiagnosticsVirtualTextMaxWidth),
 		HighlightReferences:               boolVal(c.HighlightReferences, d.HighlightReferences),
+		HighlightSemanticTokens:           boolVal(c.HighlightSemanticTokens, d.HighlightSemanticTokens),
 		HoverDiagnostics:                  boolVal(c.HoverDiagnostics, d.HoverDiagnostics),
+		DiagnosticsMinSeverity:            c.DiagnosticsMinSeverity,
+		DiagnosticsMinSeverityBySource:    copySeverityMap(c.DiagnosticsMinSeverityBySource, d.DiagnosticsMinSeverityBySource),
+		DiagnosticsMinSeverityByPath:      copySeverityMap(c.DiagnosticsMinSeverityByPath, d.DiagnosticsMinSeverityByPath),
 		CompletionDeepCompletions:         boolVal(c.CompletionDeepCompletions, d.CompletionDeepCompletions),
 		CompletionMatcher:                 c.CompletionMatcher,
 		SymbolMatcher:                     c.SymbolMatcher,
@@ -53,7 +78,17 @@ func (c *VimConfig) ToConfig(d config.Config) config.Config {
 		GoplsEnv:                          copyStringValMap(c.GoplsEnv, d.GoplsEnv),
 		GoplsDirectoryFilters:             copyStringValSlice(c.GoplsDirectoryFilters, d.GoplsDirectoryFilters),
 		Analyses:                          mergeBoolValMap(c.Analyses, d.Analyses),
+		InlayHints:                        mergeBoolValMap(c.InlayHints, d.InlayHints),
+		CodeLenses:                        mergeBoolValMap(c.CodeLenses, d.CodeLenses),
+		ShowCodeLenses:                    boolVal(c.ShowCodeLenses, d.ShowCodeLenses),
+		FoldingRanges:                     boolVal(c.FoldingRanges, d.FoldingRanges),
 		OpenLastProgressWith:              stringVal(c.OpenLastProgressWith, d.OpenLastProgressWith),
+		OpenExternalWith:                  stringVal(c.OpenExternalWith, d.OpenExternalWith),
+		MessageRequestTimeout:             stringVal(c.MessageRequestTimeout, d.MessageRequestTimeout),
+		MultiFileEditStrategy:             c.MultiFileEditStrategy,
+		MultiFileEditAutoSave:             boolVal(c.MultiFileEditAutoSave, d.MultiFileEditAutoSave),
+		MultiFileEditQuickfix:             boolVal(c.MultiFileEditQuickfix, d.MultiFileEditQuickfix),
+		MultipleLocations:                 c.MultipleLocations,
 		Gofumpt:                           boolVal(c.Gofumpt, d.Gofumpt),
 		ExperimentalAutoreadLoadedBuffers: boolVal(c.ExperimentalAutoreadLoadedBuffers, d.ExperimentalAutoreadLoadedBuffers),
 		ExperimentalMouseTriggeredHoverPopupOptions:  copyMap(c.ExperimentalMouseTriggeredHoverPopupOptions, d.ExperimentalMouseTriggeredHoverPopupOptions),
@@ -61,6 +96,7 @@ func (c *VimConfig) ToConfig(d config.Config) config.Config {
 		ExperimentalWorkaroundCompleteoptLongest:     boolVal(c.ExperimentalWorkaroundCompleteoptLongest, d.ExperimentalWorkaroundCompleteoptLongest),
 		ExperimentalProgressPopups:                   boolVal(c.ExperimentalProgressPopups, d.ExperimentalProgressPopups),
 		ExperimentalAllowModfileModifications:        boolVal(c.ExperimentalAllowModfileModifications, d.ExperimentalAllowModfileModifications),
+		ExperimentalPullDiagnostics:                  boolVal(c.ExperimentalPullDiagnostics, d.ExperimentalPullDiagnostics),
 	}
 	if v.FormatOnSave == nil {
 		v.FormatOnSave = d.FormatOnSave
@@ -74,6 +110,18 @@ func (c *VimConfig) ToConfig(d config.Config) config.Config {
 	if v.SymbolStyle == nil {
 		v.SymbolStyle = d.SymbolStyle
 	}
+	if v.DiagnosticsVirtualText == nil {
+		v.DiagnosticsVirtualText = d.DiagnosticsVirtualText
+	}
+	if v.MultiFileEditStrategy == nil {
+		v.MultiFileEditStrategy = d.MultiFileEditStrategy
+	}
+	if v.MultipleLocations == nil {
+		v.MultipleLocations = d.MultipleLocations
+	}
+	if v.DiagnosticsMinSeverity == nil {
+		v.DiagnosticsMinSeverity = d.DiagnosticsMinSeverity
+	}
 	return v
 }
 
@@ -85,6 +133,13 @@ func boolVal(i *int, j *bool) *bool {
 	return &b
 }
 
+func intVal(i, j *int) *int {
+	if i == nil {
+		return j
+	}
+	return i
+}
+
 func stringVal(i, j *string) *string {
 	if i == nil {
 		return j
@@ -107,6 +162,21 @@ func copyStringValMap(i, j *map[string]string) *map[string]string {
 	return &res
 }
 
+func copySeverityMap(i, j *map[string]config.DiagnosticSeverity) *map[string]config.DiagnosticSeverity {
+	toCopy := i
+	if i == nil {
+		toCopy = j
+		if j == nil {
+			return nil
+		}
+	}
+	res := make(map[string]config.DiagnosticSeverity)
+	for ck, cv := range *toCopy {
+		res[ck] = cv
+	}
+	return &res
+}
+
 func copyStringValSlice(i, j *[]string) *[]string {
 	toCopy := i
 	if i == nil {
@@ -162,6 +232,26 @@ func SymbolStyleVal(v config.SymbolStyle) *config.SymbolStyle {
 	return &v
 }
 
+func DiagnosticsVirtualTextVal(v config.DiagnosticsVirtualText) *config.DiagnosticsVirtualText {
+	return &v
+}
+
+func MultiFileEditStrategyVal(v config.MultiFileEditStrategy) *config.MultiFileEditStrategy {
+	return &v
+}
+
+func MultipleLocationsVal(v config.MultipleLocations) *config.MultipleLocations {
+	return &v
+}
+
+func IntVal(v int) *int {
+	return &v
+}
+
+func DiagnosticSeverityVal(v config.DiagnosticSeverity) *config.DiagnosticSeverity {
+	return &v
+}
+
 func FormatOnSaveVal(v config.FormatOnSave) *config.FormatOnSave {
 	return &v
 }
@@ -0,0 +1,359 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/govim/govim"
+	"github.com/govim/govim/cmd/govim/config"
+	"github.com/govim/govim/cmd/govim/internal/golang_org_x_tools_gopls/protocol"
+	"github.com/govim/govim/cmd/govim/internal/types"
+)
+
+const (
+	// jumpStackPropType is the type of the text properties that mark the
+	// locations of jump stack entries
+	jumpStackPropType = "GOVIMJumpStack"
+
+	// jumpStackTabVar is the tab page variable that holds the key of the jump
+	// stack of a tab page in vimstate.jumpStacks
+	jumpStackTabVar = "govim_jump_stack"
+)
+
+// jumpStack is akin to the Vim concept of a tagstack. Each tab page has its
+// own jump stack.
+type jumpStack struct {
+	entries []*jumpStackEntry
+
+	// pos is the index of the entry CommandGoToNextDef jumps to. The entries
+	// before it are those CommandGoToPrevDef returns through.
+	pos int
+}
+
+// jumpStackEntry is a jump to the location of a symbol
+type jumpStackEntry struct {
+	name string
+	from *jumpStackMark
+	to   *jumpStackMark
+}
+
+// jumpStackMark is a location of a jump stack entry. Where the location is in
+// a buffer loaded in Vim it is marked by a text property, such that the
+// location follows edits to the buffer.
+type jumpStackMark struct {
+	loc protocol.Location
+
+	// propID is the ID of the text property that marks loc, or 0 if loc is
+	// not marked
+	propID int
+}
+
+// currentJumpStack returns the jump stack of the current tab page
+func (v *vimstate) currentJumpStack() *jumpStack {
+	id := v.ParseInt(v.ChannelExprf("get(t:, %q, 0)", jumpStackTabVar))
+	if s, ok := v.jumpStacks[id]; ok {
+		return s
+	}
+	s := &jumpStack{}
+	v.setTabJumpStack(s)
+	return s
+}
+
+// setTabJumpStack makes s the jump stack of the current tab page
+func (v *vimstate) setTabJumpStack(s *jumpStack) {
+	v.lastJumpStackID++
+	v.jumpStacks[v.lastJumpStackID] = s
+	v.ChannelExf("let t:%v = %d", jumpStackTabVar, v.lastJumpStackID)
+}
+
+// jumpToLocation loads loc, the location of the symbol name, and pushes the
+// jump onto the jump stack of the current tab page. mods and args are as for
+// loadLocation.
+func (v *vimstate) jumpToLocation(mods govim.CommModList, name string, loc protocol.Location, args ...string) error {
+	s := v.currentJumpStack()
+	b, pos, err := v.bufCursorPos()
+	if err != nil {
+		// There is nowhere to return to
+		return v.loadJumpStackLocation(s, mods, loc, args...)
+	}
+	from := v.newJumpStackMark(b, *pos.Point)
+	// Push before loading, such that a tab page opened by the jump gets the
+	// entry in its copy of the stack
+	e := &jumpStackEntry{name: name, from: from, to: &jumpStackMark{loc: loc}}
+	dropped := append([]*jumpStackEntry(nil), s.entries[s.pos:]...)
+	s.entries = append(s.entries[:s.pos], e)
+	s.pos++
+	v.dropJumpStackEntries(dropped)
+	if err := v.loadJumpStackLocation(s, mods, loc, args...); err != nil {
+		return err
+	}
+	if b, pos, err := v.bufCursorPos(); err == nil {
+		e.to = v.newJumpStackMark(b, *pos.Point)
+	}
+	return nil
+}
+
+// loadJumpStackLocation loads loc, a location of the jump stack s, as
+// loadLocation does. Like the tagstack of a window is copied to a new window,
+// a tab page opened to show loc gets a copy of s.
+func (v *vimstate) loadJumpStackLocation(s *jumpStack, mods govim.CommModList, loc protocol.Location, args ...string) error {
+	tab := v.ParseInt(v.ChannelExpr("tabpagenr()"))
+	if err := v.loadLocation(mods, loc, args...); err != nil {
+		return err
+	}
+	if v.ParseInt(v.ChannelExpr("tabpagenr()")) == tab {
+		return nil
+	}
+	if _, ok := v.jumpStacks[v.ParseInt(v.ChannelExprf("get(t:, %q, 0)", jumpStackTabVar))]; !ok {
+		v.setTabJumpStack(&jumpStack{
+			entries: append([]*jumpStackEntry(nil), s.entries...),
+			pos:     s.pos,
+		})
+	}
+	return nil
+}
+
+// newJumpStackMark returns a mark of the point p in b
+func (v *vimstate) newJumpStackMark(b *types.Buffer, p types.Point) *jumpStackMark {
+	pos := p.ToPosition()
+	m := &jumpStackMark{
+		loc: protocol.Location{
+			URI:   protocol.DocumentURI(b.URI()),
+			Range: protocol.Range{Start: pos, End: pos},
+		},
+	}
+	if !b.Loaded {
+		return m
+	}
+	v.lastJumpStackPropID++
+	m.propID = types.JumpStackTextPropIDBase + v.lastJumpStackPropID
+	v.ChannelCall("prop_add", p.Line(), p.Col(), struct {
+		Type   string `json:"type"`
+		ID     int    `json:"id"`
+		Length int    `json:"length"`
+		BufNr  int    `json:"bufnr"`
+	}{jumpStackPropType, m.propID, 0, b.Num})
+	return m
+}
+
+// jumpStackMarkLocation returns the location of m, updated to follow the
+// edits to its buffer since m was created. The location is not updated if
+// the text property of m has been lost, e.g. because its line was deleted or
+// its buffer unloaded.
+func (v *vimstate) jumpStackMarkLocation(m *jumpStackMark) protocol.Location {
+	b := v.jumpStackMarkBuffer(m)
+	if b == nil {
+		return m.loc
+	}
+	var prop struct {
+		Line int `json:"lnum"`
+		Col  int `json:"col"`
+	}
+	v.Parse(v.ChannelCall("prop_find", struct {
+		ID    int `json:"id"`
+		BufNr int `json:"bufnr"`
+		Line  int `json:"lnum"`
+		Col   int `json:"col"`
+	}{m.propID, b.Num, 1, 1}, "f"), &prop)
+	if prop.Line == 0 {
+		return m.loc
+	}
+	p, err := types.PointFromVim(b, prop.Line, prop.Col)
+	if err != nil {
+		v.Logf("failed to derive point from jump stack text property: %v", err)
+		return m.loc
+	}
+	pos := p.ToPosition()
+	m.loc.Range = protocol.Range{Start: pos, End: pos}
+	return m.loc
+}
+
+// jumpStackMarkBuffer returns the loaded buffer in which m has a text
+// property, or nil if there is none
+func (v *vimstate) jumpStackMarkBuffer(m *jumpStackMark) *types.Buffer {
+	if m.propID == 0 {
+		return nil
+	}
+	for _, b := range v.buffers {
+		if b.Loaded && b.URI() == m.loc.URI {
+			return b
+		}
+	}
+	return nil
+}
+
+// dropJumpStackEntries removes the text properties of entries, which have
+// been removed from a jump stack, unless they remain in the jump stack of
+// another tab page.
+func (v *vimstate) dropJumpStackEntries(entries []*jumpStackEntry) {
+	inUse := make(map[*jumpStackEntry]bool)
+	for _, s := range v.jumpStacks {
+		for _, e := range s.entries {
+			inUse[e] = true
+		}
+	}
+	for _, e := range entries {
+		if inUse[e] {
+			continue
+		}
+		for _, m := range []*jumpStackMark{e.from, e.to} {
+			b := v.jumpStackMarkBuffer(m)
+			if b == nil {
+				continue
+			}
+			v.ChannelCall("prop_remove", struct {
+				ID    int `json:"id"`
+				BufNr int `json:"bufnr"`
+			}{m.propID, b.Num})
+		}
+	}
+}
+
+// tabClosed drops the jump stacks of tab pages that have been closed
+func (v *vimstate) tabClosed(args ...json.RawMessage) error {
+	var ids []int
+	v.Parse(v.ChannelExprf(`map(range(1, tabpagenr("$")), {_, t -> gettabvar(t, %q, 0)})`, jumpStackTabVar), &ids)
+	open := make(map[int]bool)
+	for _, id := range ids {
+		open[id] = true
+	}
+	var dropped []*jumpStackEntry
+	for id, s := range v.jumpStacks {
+		if !open[id] {
+			delete(v.jumpStacks, id)
+			dropped = append(dropped, s.entries...)
+		}
+	}
+	v.dropJumpStackEntries(dropped)
+	return nil
+}
+
+func (v *vimstate) gotoPrevDef(flags govim.CommandFlags, args ...string) error {
+	s := v.currentJumpStack()
+	if s.pos == 0 {
+		v.ChannelEx(`echom "Already at top of stack"`)
+		return nil
+	}
+	s.pos -= jumpStackCount(flags)
+	if s.pos < 0 {
+		s.pos = 0
+	}
+	loc := v.jumpStackMarkLocation(s.entries[s.pos].from)
+	return v.loadJumpStackLocation(s, flags.Mods, loc, args...)
+}
+
+func (v *vimstate) gotoNextDef(flags govim.CommandFlags, args ...string) error {
+	s := v.currentJumpStack()
+	if s.pos == len(s.entries) {
+		v.ChannelEx(`echom "Already at bottom of stack"`)
+		return nil
+	}
+	s.pos += jumpStackCount(flags)
+	if s.pos > len(s.entries) {
+		s.pos = len(s.entries)
+	}
+	loc := v.jumpStackMarkLocation(s.entries[s.pos-1].to)
+	return v.loadJumpStackLocation(s, flags.Mods, loc, args...)
+}
+
+// jumpStackCount returns the number of entries by which CommandGoToPrevDef
+// or CommandGoToNextDef moves. Vim passes a count of 0 through, e.g. for
+// :0GOVIMGoToNextDef, which moves by one entry like no count at all.
+func jumpStackCount(flags govim.CommandFlags) int {
+	if *flags.Count < 1 {
+		return 1
+	}
+	return *flags.Count
+}
+
+// jumpStackPopup is the state of the popup opened by CommandJumpStack
+type jumpStackPopup struct {
+	popupID int
+	stack   *jumpStack
+
+	// mods are the command modifiers CommandJumpStack was called with, used
+	// when loading the selected entry
+	mods govim.CommModList
+}
+
+// jumpStackList opens a popup menu of the entries of the jump stack of the
+// current tab page. Selecting an entry returns to where its symbol was jumped
+// to from, as if by CommandGoToPrevDef or CommandGoToNextDef.
+func (v *vimstate) jumpStackList(flags govim.CommandFlags, args ...string) error {
+	v.closeJumpStackPopup()
+	s := v.currentJumpStack()
+	if len(s.entries) == 0 {
+		v.ChannelEx(`echom "Jump stack is empty"`)
+		return nil
+	}
+	opts := map[string]interface{}{
+		"title":    " Jump stack ",
+		"callback": "GOVIM" + config.FunctionJumpStackSelection,
+	}
+	p := &jumpStackPopup{
+		stack: s,
+		mods:  flags.Mods,
+	}
+	p.popupID = v.ParseInt(v.ChannelCall("popup_menu", v.jumpStackLines(s), opts))
+	v.jumpStackPopup = p
+	curr := s.pos + 1
+	if curr > len(s.entries) {
+		curr = len(s.entries)
+	}
+	v.ChannelCall("win_execute", p.popupID, fmt.Sprintf("call cursor(%d, 1)", curr))
+	return nil
+}
+
+// jumpStackLines formats the entries of s as aligned columns of number,
+// symbol name and the file:line the symbol was jumped to from. As with
+// :tags, the entry at the current position in the stack is marked by '>'.
+func (v *vimstate) jumpStackLines(s *jumpStack) []string {
+	var nameWidth int
+	for _, e := range s.entries {
+		if n := len(e.name); n > nameWidth {
+			nameWidth = n
+		}
+	}
+	lines := make([]string, len(s.entries))
+	for i, e := range s.entries {
+		curr := " "
+		if i == s.pos {
+			curr = ">"
+		}
+		loc := v.jumpStackMarkLocation(e.from)
+		lines[i] = fmt.Sprintf("%v%2d  %-*s  %v:%v", curr, i+1, nameWidth, e.name, v.diffPath(loc.URI), loc.Range.Start.Line+1)
+	}
+	return lines
+}
+
+// jumpStackSelection is the callback of the jump stack popup. selected is
+// the 1-indexed line of the selected entry, or less than 1 if the popup was
+// closed without a selection.
+func (v *vimstate) jumpStackSelection(args ...json.RawMessage) (interface{}, error) {
+	var popupID int
+	var selected int
+	v.Parse(args[0], &popupID)
+	v.Parse(args[1], &selected)
+	p := v.jumpStackPopup
+	if p == nil || p.popupID != popupID {
+		return nil, nil
+	}
+	v.jumpStackPopup = nil
+	s := p.stack
+	if selected < 1 || selected > len(s.entries) {
+		return nil, nil
+	}
+	s.pos = selected - 1
+	loc := v.jumpStackMarkLocation(s.entries[s.pos].from)
+	return nil, v.loadJumpStackLocation(s, p.mods, loc)
+}
+
+// closeJumpStackPopup closes the jump stack popup, if open
+func (v *vimstate) closeJumpStackPopup() {
+	p := v.jumpStackPopup
+	if p == nil {
+		return
+	}
+	v.jumpStackPopup = nil
+	v.ChannelCall("popup_close", p.popupID)
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"encoding/json"
+
+	"github.com/govim/govim/cmd/govim/internal/types"
+)
+
+// windowInfo is the subset of the information returned by getwininfo() that
+// is required to populate location lists
+type windowInfo struct {
+	WinID int `json:"winid"`
+	BufNr int `json:"bufnr"`
+
+	// Quickfix is 1 for quickfix and location list windows
+	Quickfix int `json:"quickfix"`
+}
+
+func (v *vimstate) locationListsEnabled() bool {
+	return v.config.LocationListDiagnostics != nil && *v.config.LocationListDiagnostics
+}
+
+// updateLocationListsWithDiagnostics updates the location list of each
+// window with the current diagnostics() of the buffer shown in the window,
+// unless the diagnostics have not changed and force is false.
+func (v *vimstate) updateLocationListsWithDiagnostics(force bool) error {
+	if !v.locationListsEnabled() {
+		return nil
+	}
+	diags := v.diagnostics()
+	diagsHasChanged := v.lastDiagnosticsLocationLists != diags
+	v.lastDiagnosticsLocationLists = diags
+	if !force && !diagsHasChanged {
+		return nil
+	}
+	var wins []windowInfo
+	v.Parse(v.ChannelCall("getwininfo"), &wins)
+	v.setLocationListDiagnostics(diags, wins)
+	return nil
+}
+
+// bufWinEnter updates the location list of a window when a buffer is shown in
+// it, such that the list holds the diagnostics of that buffer.
+func (v *vimstate) bufWinEnter(args ...json.RawMessage) error {
+	if !v.locationListsEnabled() {
+		return nil
+	}
+	var wins []windowInfo
+	v.Parse(v.ChannelCall("getwininfo", v.ParseInt(args[0])), &wins)
+	v.setLocationListDiagnostics(v.diagnostics(), wins)
+	return nil
+}
+
+// setLocationListDiagnostics fills the location list of each of wins with
+// the diagnostics of the buffer shown in the window. As for the quickfix
+// list, a location list is only replaced if it is empty or already holds
+// diagnostics, such that the results of e.g. :lvimgrep are retained.
+func (v *vimstate) setLocationListDiagnostics(diags *[]types.Diagnostic, wins []windowInfo) {
+	byBuf := make(map[int][]quickfixEntry)
+	for _, d := range *diags {
+		if d.Buf < 1 {
+			continue
+		}
+		if f, ok := v.diagnosticQuickfixEntry(d); ok {
+			byBuf[d.Buf] = append(byBuf[d.Buf], f)
+		}
+	}
+
+	v.BatchStart()
+	for _, w := range wins {
+		v.BatchChannelCall("getloclist", w.WinID, map[string]int{"idx": 0, "size": 0, "title": 0})
+	}
+	res := v.MustBatchEnd()
+
+	v.BatchStart()
+	for i, w := range wins {
+		if w.Quickfix != 0 {
+			continue
+		}
+		var loclist qflistProps
+		v.Parse(res[i], &loclist)
+		if loclist.Size > 0 && loclist.Title != quickfixDiagnosticsTitle {
+			continue
+		}
+		// must be non-nil
+		fixes := []quickfixEntry{}
+		fixes = append(fixes, byBuf[w.BufNr]...)
+		if loclist.Size == 0 && len(fixes) == 0 {
+			continue
+		}
+		// Note: indexes are 1-based. Retain the selected entry where possible.
+		idx := loclist.Idx
+		if idx > len(fixes) {
+			idx = len(fixes)
+		}
+		v.BatchChannelCall("setloclist", w.WinID, fixes, "r")
+		v.BatchChannelCall("setloclist", w.WinID, []quickfixEntry{}, "r", qflistProps{Title: quickfixDiagnosticsTitle, Idx: idx})
+	}
+	v.MustBatchEnd()
+}
+
+// clearLocationListDiagnostics empties the location lists that hold
+// diagnostics.
+func (v *vimstate) clearLocationListDiagnostics() {
+	var wins []windowInfo
+	v.Parse(v.ChannelCall("getwininfo"), &wins)
+	v.BatchStart()
+	for _, w := range wins {
+		v.BatchChannelCall("getloclist", w.WinID, map[string]int{"size": 0, "title": 0})
+	}
+	res := v.MustBatchEnd()
+
+	v.BatchStart()
+	for i, w := range wins {
+		var loclist qflistProps
+		v.Parse(res[i], &loclist)
+		if loclist.Size > 0 && loclist.Title == quickfixDiagnosticsTitle {
+			v.BatchChannelCall("setloclist", w.WinID, []quickfixEntry{}, "r")
+		}
+	}
+	v.MustBatchEnd()
+	v.lastDiagnosticsLocationLists = nil
+}
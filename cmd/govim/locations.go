@@ -0,0 +1,80 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/govim/govim"
+	"github.com/govim/govim/cmd/govim/config"
+	"github.com/govim/govim/cmd/govim/internal/golang_org_x_tools_gopls/protocol"
+)
+
+// locationsPicker is the state of the popup menu opened by gotoLocations to
+// choose between several locations.
+type locationsPicker struct {
+	popupID int
+	locs    []protocol.Location
+
+	// name is the symbol of which the locations were looked up
+	name string
+
+	// mods and args are those of the command that opened the picker, used
+	// when loading the selected location
+	mods govim.CommModList
+	args []string
+}
+
+// openLocationsPicker opens a popup menu of locs, one line per entry of
+// entries, from which the location to jump to is selected.
+func (v *vimstate) openLocationsPicker(mods govim.CommModList, name, what string, locs []protocol.Location, entries []quickfixEntry, args ...string) error {
+	v.closeLocationsPicker()
+	lines := make([]string, len(entries))
+	for i, e := range entries {
+		lines[i] = fmt.Sprintf("%v:%v: %v", e.Filename, e.Lnum, strings.TrimSpace(e.Text))
+	}
+	opts := map[string]interface{}{
+		"line":     "cursor+1",
+		"col":      "cursor",
+		"title":    fmt.Sprintf(" %v %vs ", len(locs), what),
+		"callback": "GOVIM" + config.FunctionLocationsSelection,
+	}
+	p := &locationsPicker{
+		locs: locs,
+		name: name,
+		mods: mods,
+		args: args,
+	}
+	p.popupID = v.ParseInt(v.ChannelCall("popup_menu", lines, opts))
+	v.locationsPicker = p
+	return nil
+}
+
+// locationsSelection is the callback of the locations picker popup. selected
+// is the 1-indexed line of the selected location, or less than 1 if the popup
+// was closed without a selection.
+func (v *vimstate) locationsSelection(args ...json.RawMessage) (interface{}, error) {
+	var popupID int
+	var selected int
+	v.Parse(args[0], &popupID)
+	v.Parse(args[1], &selected)
+	p := v.locationsPicker
+	if p == nil || p.popupID != popupID {
+		return nil, nil
+	}
+	v.locationsPicker = nil
+	if selected < 1 || selected > len(p.locs) {
+		return nil, nil
+	}
+	return nil, v.jumpToLocation(p.mods, p.name, p.locs[selected-1], p.args...)
+}
+
+// closeLocationsPicker closes the locations picker popup, if open
+func (v *vimstate) closeLocationsPicker() {
+	p := v.locationsPicker
+	if p == nil {
+		return
+	}
+	v.locationsPicker = nil
+	v.ChannelCall("popup_close", p.popupID)
+}
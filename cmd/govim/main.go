@@ -165,18 +165,36 @@ type govimplugin struct {
 
 	isGui bool
 
+	// hasVirtualText indicates whether Vim supports text properties with
+	// virtual text, i.e. the "text" property of prop_add()
+	hasVirtualText bool
+
+	// hasVirtualTextAbove indicates whether Vim supports virtual text above a
+	// line, i.e. the "above" value of the "text_align" property of prop_add()
+	hasVirtualTextAbove bool
+
+	// hasVirtualTextAfter indicates whether Vim supports virtual text after
+	// the end of a line, i.e. the "after" value of the "text_align" property
+	// of prop_add(), along with the "text_wrap" property
+	hasVirtualTextAfter bool
+
 	tomb tomb.Tomb
 
 	modWatcher *modWatcher
 
 	// diagnosticsChangedLock protects access to rawDiagnostics,
-	// diagnosticsChanged, diagnosticsChangedQuickfix,
+	// pullDiagnostics, diagnosticsChanged, diagnosticsChangedQuickfix,
 	// diagnosticsChangedSigns and diagnosticsChangedHighlights
 	diagnosticsChangedLock sync.Mutex
 
 	// rawDiagnostics holds the current raw (LSP) diagnostics by URI
 	rawDiagnostics map[protocol.DocumentURI]*protocol.PublishDiagnosticsParams
 
+	// pullDiagnostics holds the diagnostic options of gopls when diagnostics
+	// are pulled via gopls.Diagnostic rather than published by gopls, and is
+	// nil otherwise
+	pullDiagnostics *protocol.DiagnosticOptions
+
 	// diagnosticsChanged indicates that the new diagnostics are available
 	diagnosticsChanged bool
 
@@ -184,6 +202,19 @@ type govimplugin struct {
 	// when updating the quickfix window
 	lastDiagnosticsQuickfix *[]types.Diagnostic
 
+	// lastDiagnosticsLocationLists records the last diagnostics that were
+	// used when populating location lists
+	lastDiagnosticsLocationLists *[]types.Diagnostic
+
+	// diagnosticsVirtualTextCursor is the cursor line when the user was last
+	// idle, for which virtual text is shown in DiagnosticsVirtualTextCursorLine
+	// mode
+	diagnosticsVirtualTextCursor diagnosticsVirtualTextLine
+
+	// lastDiagnosticsUserEvent records the last diagnostics for which the
+	// diagnosticsChangedEvent User autocommand was fired
+	lastDiagnosticsUserEvent *[]types.Diagnostic
+
 	// lastDiagnosticsSigns records the last diagnostics that were used when
 	// updating signs
 	lastDiagnosticsSigns *[]types.Diagnostic
@@ -209,6 +240,11 @@ type govimplugin struct {
 	applyEditsCh   chan applyEditCall
 	applyEditsLock sync.Mutex
 
+	// messageRequestsCh is, like applyEditsCh, used to pass incoming prompts
+	// (ShowMessageRequest) to the main thread during a blocking call. Setting
+	// and unsetting this channel is also protected by applyEditsLock.
+	messageRequestsCh chan *messageRequest
+
 	bufferUpdates chan *bufferUpdate
 
 	// inShutdown is closed when govim is told to Shutdown
@@ -241,14 +277,28 @@ func newplugin(goplspath string, goplsEnv []string, defaults, user *config.Confi
 			FormatOnSave:                      vimconfig.FormatOnSaveVal(config.FormatOnSaveGoImportsGoFmt),
 			QuickfixAutoDiagnostics:           vimconfig.BoolVal(true),
 			QuickfixSigns:                     vimconfig.BoolVal(true),
+			LocationListDiagnostics:           vimconfig.BoolVal(false),
 			Staticcheck:                       vimconfig.BoolVal(false),
 			HighlightDiagnostics:              vimconfig.BoolVal(true),
+			DiagnosticsVirtualText:            vimconfig.DiagnosticsVirtualTextVal(config.DiagnosticsVirtualTextOff),
+			DiagnosticsVirtualTextMaxWidth:    vimconfig.IntVal(80),
 			HighlightReferences:               vimconfig.BoolVal(true),
+			HighlightSemanticTokens:           vimconfig.BoolVal(false),
+			ShowCodeLenses:                    vimconfig.BoolVal(false),
+			FoldingRanges:                     vimconfig.BoolVal(false),
 			HoverDiagnostics:                  vimconfig.BoolVal(true),
+			DiagnosticsMinSeverity:            vimconfig.DiagnosticSeverityVal(config.DiagnosticSeverityHint),
 			ExperimentalAutoreadLoadedBuffers: vimconfig.BoolVal(false),
 			SymbolMatcher:                     vimconfig.SymbolMatcherVal(config.SymbolMatcherFuzzy),
 			SymbolStyle:                       vimconfig.SymbolStyleVal(config.SymbolStyleFull),
 			OpenLastProgressWith:              vimconfig.StringVal("below 10split"),
+			OpenExternalWith:                  vimconfig.StringVal("xdg-open"),
+			MessageRequestTimeout:             vimconfig.StringVal("1m"),
+			MultiFileEditStrategy:             vimconfig.MultiFileEditStrategyVal(config.MultiFileEditStrategySplit),
+			MultiFileEditAutoSave:             vimconfig.BoolVal(false),
+			MultiFileEditQuickfix:             vimconfig.BoolVal(false),
+			MultipleLocations:                 vimconfig.MultipleLocationsVal(config.MultipleLocationsPopup),
+			ExperimentalPullDiagnostics:       vimconfig.BoolVal(false),
 		}
 	}
 	// Overlay the initial user values on the defaults
@@ -267,12 +317,20 @@ func newplugin(goplspath string, goplsEnv []string, defaults, user *config.Confi
 		inShutdown:       make(chan struct{}),
 		diagnosticsCache: &emptyDiags,
 		vimstate: &vimstate{
-			Driver:               d,
-			buffers:              make(map[int]*types.Buffer),
-			defaultConfig:        *defaults,
-			config:               *defaults,
-			suggestedFixesPopups: make(map[int][]suggestedFix),
-			progressPopups:       make(map[protocol.ProgressToken]*types.ProgressPopup),
+			Driver:                d,
+			buffers:               make(map[int]*types.Buffer),
+			defaultConfig:         *defaults,
+			config:                *defaults,
+			suggestedFixesPopups:  make(map[int][]suggestedFix),
+			progressPopups:        make(map[protocol.ProgressToken]*types.ProgressPopup),
+			treeViews:             make(map[int]*treeView),
+			jumpStacks:            make(map[int]*jumpStack),
+			semanticTokens:        make(map[int]*semanticTokensState),
+			cancelCodeLenses:      make(map[int]context.CancelFunc),
+			foldingRanges:         make(map[int]*foldingRangesState),
+			messageRequests:       make(map[int]*messageRequest),
+			cancelPullDiagnostics: make(map[int]context.CancelFunc),
+			diagnosticResultIDs:   make(map[protocol.DocumentURI]string),
 		},
 	}
 	res.vimstate.govimplugin = res
@@ -295,6 +353,7 @@ func (g *govimplugin) Init(gg govim.Govim, errCh chan error) error {
 	g.DefineAutoCommand("", govim.Events{govim.EventBufRead, govim.EventBufNewFile}, govim.Patterns{"*.go", "go.mod", "go.sum"}, false, g.vimstate.bufReadPost, exprAutocmdCurrBufInfo)
 	g.DefineAutoCommand("", govim.Events{govim.EventBufWritePre}, govim.Patterns{"*.go", "go.mod", "go.sum"}, false, g.vimstate.formatCurrentBuffer, "eval(expand('<abuf>'))")
 	g.DefineAutoCommand("", govim.Events{govim.EventBufWritePost}, govim.Patterns{"*.go", "go.mod", "go.sum"}, false, g.vimstate.bufWritePost, "eval(expand('<abuf>'))")
+	g.DefineAutoCommand("", govim.Events{govim.EventBufWinEnter}, govim.Patterns{"*"}, false, g.vimstate.bufWinEnter, "win_getid()")
 	g.DefineAutoCommand("", govim.Events{govim.EventQuickFixCmdPre}, govim.Patterns{"*vimgrep*"}, false, g.vimstate.bufQuickFixCmdPre)
 	g.DefineAutoCommand("", govim.Events{govim.EventQuickFixCmdPost}, govim.Patterns{"*vimgrep*"}, false, g.vimstate.bufQuickFixCmdPost)
 	g.DefineFunction(string(config.FunctionComplete), []string{"findarg", "base"}, g.vimstate.complete)
@@ -302,6 +361,12 @@ func (g *govimplugin) Init(gg govim.Govim, errCh chan error) error {
 	g.DefineCommand(string(config.CommandGoToTypeDef), g.vimstate.gotoTypeDef, govim.NArgsZeroOrOne)
 	g.DefineCommand(string(config.CommandSuggestedFixes), g.vimstate.suggestFixes, govim.NArgsZeroOrOne)
 	g.DefineCommand(string(config.CommandGoToPrevDef), g.vimstate.gotoPrevDef, govim.NArgsZeroOrOne, govim.CountN(1))
+	g.DefineCommand(string(config.CommandGoToNextDef), g.vimstate.gotoNextDef, govim.NArgsZeroOrOne, govim.CountN(1))
+	g.DefineCommand(string(config.CommandGoToDecl), g.vimstate.gotoDecl, govim.NArgsZeroOrOne)
+	g.DefineCommand(string(config.CommandJumpStack), g.vimstate.jumpStackList, govim.NArgs0)
+	g.DefineFunction(string(config.FunctionJumpStackSelection), []string{"id", "selected"}, g.vimstate.jumpStackSelection)
+	g.DefineAutoCommand("", govim.Events{govim.EventTabClosed}, govim.Patterns{"*"}, false, g.vimstate.tabClosed)
+	g.DefineFunction(string(config.FunctionLocationsSelection), []string{"id", "selected"}, g.vimstate.locationsSelection)
 	g.DefineFunction(string(config.FunctionHover), []string{}, g.vimstate.hover)
 	g.DefineAutoCommand("", govim.Events{govim.EventBufDelete}, govim.Patterns{"*.go", "go.mod", "go.sum"}, false, g.vimstate.bufDelete, "eval(expand('<abuf>'))")
 	g.DefineAutoCommand("", govim.Events{govim.EventBufWipeout}, govim.Patterns{"*.go", "go.mod", "go.sum"}, false, g.vimstate.bufWipeout, "eval(expand('<abuf>'))")
@@ -315,7 +380,7 @@ func (g *govimplugin) Init(gg govim.Govim, errCh chan error) error {
 	g.DefineFunction(string(config.FunctionPopupSelection), []string{"id", "selected"}, g.vimstate.popupSelection)
 	g.DefineCommand(string(config.CommandReferences), g.vimstate.references)
 	g.DefineCommand(string(config.CommandImplements), g.vimstate.implements)
-	g.DefineCommand(string(config.CommandRename), g.vimstate.rename, govim.NArgsZeroOrOne)
+	g.DefineCommand(string(config.CommandRename), g.vimstate.rename, govim.AttrBang, govim.NArgsZeroOrOne)
 	g.DefineCommand(string(config.CommandStringFn), g.vimstate.stringfns, govim.RangeLine, govim.CompleteCustomList(PluginPrefix+config.FunctionStringFnComplete), govim.NArgsOneOrMore)
 	g.DefineFunction(string(config.FunctionStringFnComplete), []string{"ArgLead", "CmdLine", "CursorPos"}, g.vimstate.stringfncomplete)
 	g.DefineCommand(string(config.CommandHighlightReferences), g.vimstate.highlightReferences)
@@ -328,6 +393,28 @@ func (g *govimplugin) Init(gg govim.Govim, errCh chan error) error {
 	g.DefineCommand(string(config.CommandGoTest), g.vimstate.runGoTest, govim.RangeLine)
 	g.DefineFunction(string(config.FunctionProgressClosed), []string{"id", "selected"}, g.vimstate.progressClosed)
 	g.DefineCommand(string(config.CommandLastProgress), g.vimstate.openLastProgress)
+	g.DefineCommand(string(config.CommandCallersOf), g.vimstate.callersOf, govim.AttrBang)
+	g.DefineCommand(string(config.CommandCalleesOf), g.vimstate.calleesOf, govim.AttrBang)
+	g.DefineCommand(string(config.CommandTypeHierarchy), g.vimstate.typeHierarchy, govim.NArgsZeroOrOne, govim.CompleteCustomList(PluginPrefix+config.FunctionTypeHierarchyComplete))
+	g.DefineFunction(string(config.FunctionTypeHierarchyComplete), []string{"ArgLead", "CmdLine", "CursorPos"}, g.vimstate.typeHierarchyComplete)
+	g.DefineFunction(string(config.FunctionTreeAction), []string{"action", "bufnr", "line"}, g.vimstate.treeAction)
+	g.DefineCommand(string(config.CommandCodeAction), g.vimstate.codeAction, govim.RangeLine, govim.NArgsZeroOrOne)
+	g.DefineCommand(string(config.CommandExtractFunction), g.vimstate.extractFunction, govim.RangeLine, govim.NArgsZeroOrOne)
+	g.DefineCommand(string(config.CommandExtractMethod), g.vimstate.extractMethod, govim.RangeLine, govim.NArgsZeroOrOne)
+	g.DefineCommand(string(config.CommandExtractVariable), g.vimstate.extractVariable, govim.RangeLine, govim.NArgsZeroOrOne)
+	g.DefineCommand(string(config.CommandOutline), g.vimstate.toggleOutline)
+	g.DefineCommand(string(config.CommandSymbols), g.vimstate.symbols, govim.NArgsZeroOrMore)
+	g.DefineFunction(string(config.FunctionSymbolsQuery), []string{"id", "query"}, g.vimstate.symbolsQuery)
+	g.DefineFunction(string(config.FunctionSymbolsSelection), []string{"id", "selected"}, g.vimstate.symbolsSelection)
+	g.DefineCommand(string(config.CommandCodeLens), g.vimstate.codeLens)
+	g.DefineCommand(string(config.CommandDiagnosticNext), g.vimstate.diagnosticNext, govim.AttrBang, govim.NArgsZeroOrOne, govim.CountN(1), govim.CompleteCustomList(PluginPrefix+config.FunctionDiagnosticSeverityComplete))
+	g.DefineCommand(string(config.CommandDiagnosticPrev), g.vimstate.diagnosticPrev, govim.AttrBang, govim.NArgsZeroOrOne, govim.CountN(1), govim.CompleteCustomList(PluginPrefix+config.FunctionDiagnosticSeverityComplete))
+	g.DefineCommand(string(config.CommandDiagnosticRelated), g.vimstate.diagnosticRelated, govim.CountN(1))
+	g.DefineCommand(string(config.CommandFixAll), g.vimstate.fixAll, govim.AttrBang, govim.NArgs1, govim.CompleteCustomList(PluginPrefix+config.FunctionFixAllComplete))
+	g.DefineFunction(string(config.FunctionFixAllComplete), []string{"ArgLead", "CmdLine", "CursorPos"}, g.vimstate.fixAllComplete)
+	g.DefineFunction(string(config.FunctionDiagnosticCounts), nil, g.vimstate.diagnosticCounts)
+	g.DefineFunction(string(config.FunctionDiagnosticSeverityComplete), []string{"ArgLead", "CmdLine", "CursorPos"}, g.vimstate.diagnosticSeverityComplete)
+	g.DefineFunction(string(config.FunctionMessageRequestSelection), []string{"id", "selected"}, g.vimstate.messageRequestSelection)
 	g.defineHighlights()
 	if err := g.vimstate.signDefine(); err != nil {
 		return fmt.Errorf("failed to define signs: %v", err)
@@ -342,6 +429,14 @@ func (g *govimplugin) Init(gg govim.Govim, errCh chan error) error {
 	g.InitTestAPI()
 
 	g.isGui = g.ParseInt(g.ChannelExpr(`has("gui_running")`)) == 1
+	g.hasVirtualText = g.ParseInt(g.ChannelExpr(`has("patch-9.0.0067")`)) == 1
+	g.hasVirtualTextAbove = g.ParseInt(g.ChannelExpr(`has("patch-9.0.0200")`)) == 1
+	g.hasVirtualTextAfter = g.hasVirtualTextAbove
+	if g.hasVirtualText {
+		// WinScrolled is not available in all supported versions of Vim, but
+		// is in those with virtual text, on which inlay hints depend
+		g.DefineAutoCommand("", govim.Events{govim.EventWinScrolled}, govim.Patterns{"*"}, false, g.vimstate.winScrolled)
+	}
 
 	if err := g.startGopls(); err != nil {
 		return err
@@ -439,7 +534,7 @@ func (g *govimplugin) defineHighlights() {
 		diagSrcColor = 7 // Silver
 	}
 	g.vimstate.BatchStart()
-	for _, hi := range []string{
+	for _, hi := range append([]string{
 		fmt.Sprintf("highlight default %s term=underline cterm=underline gui=undercurl ctermfg=1 guisp=Red", config.HighlightErr),
 		fmt.Sprintf("highlight default %s term=underline cterm=underline gui=undercurl ctermfg=%d guisp=Orange", config.HighlightWarn, warnColor),
 		fmt.Sprintf("highlight default %s term=underline cterm=underline gui=undercurl ctermfg=6 guisp=Cyan", config.HighlightInfo),
@@ -456,15 +551,21 @@ func (g *govimplugin) defineHighlights() {
 		fmt.Sprintf("highlight default link %s %s", config.HighlightHoverHint, config.HighlightHoverInfo),
 
 		fmt.Sprintf("highlight default %s cterm=none gui=italic ctermfg=%d guifg=#8a8a8a", config.HighlightHoverDiagSrc, diagSrcColor),
+		fmt.Sprintf("highlight default link %s Comment", config.HighlightUnnecessary),
+		fmt.Sprintf("highlight default %s term=strikethrough cterm=strikethrough gui=strikethrough", config.HighlightDeprecated),
 
 		fmt.Sprintf("highlight default %s term=reverse cterm=reverse gui=reverse", config.HighlightReferences),
 
 		fmt.Sprintf("highlight default link %s PMenu", config.HighlightSignature),
 		fmt.Sprintf("highlight default %s term=bold cterm=bold gui=bold", config.HighlightSignatureParam),
 
+		fmt.Sprintf("highlight default link %s Comment", config.HighlightInlayHint),
+		fmt.Sprintf("highlight default link %s SpecialComment", config.HighlightCodeLens),
+		fmt.Sprintf("highlight default link %s Visual", config.HighlightOutlineCurrent),
+
 		fmt.Sprintf("highlight default %s ctermfg=2 guifg=Green", config.HighlightGoTestPass),
 		fmt.Sprintf("highlight default %s ctermfg=1 guifg=Red ", config.HighlightGoTestFail),
-	} {
+	}, semanticTokensHighlightCmds()...) {
 		g.vimstate.BatchChannelCall("execute", hi)
 	}
 	g.vimstate.MustBatchEnd()
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/govim/govim/cmd/govim/config"
+	"github.com/govim/govim/cmd/govim/internal/golang_org_x_tools_gopls/protocol"
+)
+
+// messageRequest is a prompt from gopls, i.e. a message along with the
+// actions the user can choose from.
+type messageRequest struct {
+	params *protocol.ShowMessageRequestParams
+
+	// popupID is the ID of the popup showing the prompt, or 0 if it has yet
+	// to be shown
+	popupID int
+
+	// res receives the chosen action, or nil if the prompt was dismissed. It
+	// is buffered such that sending never blocks the Vim thread.
+	res chan *protocol.MessageActionItem
+}
+
+// showMessageRequest shows the prompt req in a popup. The message is followed
+// by the actions, of which the selected one is chosen via
+// messageRequestSelection. A prompt without actions is only informative, and
+// is responded to immediately.
+func (v *vimstate) showMessageRequest(req *messageRequest) error {
+	params := req.params
+	lines := strings.Split(params.Message, "\n")
+	opts := map[string]interface{}{
+		"pos":     "center",
+		"padding": []int{0, 1, 0, 1},
+		"wrap":    true,
+		"border":  []int{},
+		"title":   " gopls ",
+		"mapping": 0,
+	}
+	switch params.Type {
+	case protocol.Error:
+		opts["borderhighlight"] = []string{"ErrorMsg"}
+	case protocol.Warning:
+		opts["borderhighlight"] = []string{"WarningMsg"}
+	}
+	if len(params.Actions) == 0 {
+		opts["moved"] = "any"
+		opts["close"] = "click"
+		req.popupID = v.ParseInt(v.ChannelCall("popup_create", lines, opts))
+		req.res <- nil
+		return nil
+	}
+
+	// Only the lines of the actions can be selected by the popup filter
+	first := len(lines) + 2
+	lines = append(lines, "")
+	for _, a := range params.Actions {
+		lines = append(lines, a.Title)
+	}
+	opts["cursorline"] = 1
+	opts["filter"] = "GOVIM_internal_MessageRequestFilter"
+	opts["callback"] = "GOVIM" + config.FunctionMessageRequestSelection
+	req.popupID = v.ParseInt(v.ChannelCall("popup_create", lines, opts))
+	v.messageRequests[req.popupID] = req
+	v.BatchStart()
+	v.BatchChannelCall("setwinvar", req.popupID, "govim_message_request_first", first)
+	v.BatchChannelCall("win_execute", req.popupID, fmt.Sprintf("call cursor(%d, 1)", first))
+	v.MustBatchEnd()
+	return nil
+}
+
+// promptMessageRequest prompts for an action of req using inputlist(). It is
+// used instead of showMessageRequest during a blocking call on the Vim thread,
+// when Vim waits on govim and so cannot handle the keys of a popup.
+func (v *vimstate) promptMessageRequest(req *messageRequest) error {
+	params := req.params
+	if len(params.Actions) == 0 {
+		return v.showMessageRequest(req)
+	}
+	lines := strings.Split(params.Message, "\n")
+	for i, a := range params.Actions {
+		lines = append(lines, fmt.Sprintf("%d. %v", i+1, a.Title))
+	}
+	selected := v.ParseInt(v.ChannelCall("inputlist", lines))
+	if selected < 1 || selected > len(params.Actions) {
+		req.res <- nil
+		return nil
+	}
+	req.res <- &params.Actions[selected-1]
+	return nil
+}
+
+// messageRequestSelection is the callback of the popup of a prompt. selected
+// is the 1-indexed action chosen, or less than 1 if the prompt was dismissed.
+func (v *vimstate) messageRequestSelection(args ...json.RawMessage) (interface{}, error) {
+	var popupID int
+	var selected int
+	v.Parse(args[0], &popupID)
+	v.Parse(args[1], &selected)
+	req, ok := v.messageRequests[popupID]
+	if !ok {
+		// The prompt has timed out or been cancelled
+		return nil, nil
+	}
+	delete(v.messageRequests, popupID)
+	if selected < 1 || selected > len(req.params.Actions) {
+		req.res <- nil
+		return nil, nil
+	}
+	req.res <- &req.params.Actions[selected-1]
+	return nil, nil
+}
+
+// closeMessageRequest closes the popup of the prompt req, if it is still
+// open, without responding to it.
+func (v *vimstate) closeMessageRequest(req *messageRequest) {
+	if _, ok := v.messageRequests[req.popupID]; !ok {
+		return
+	}
+	delete(v.messageRequests, req.popupID)
+	v.ChannelCall("popup_close", req.popupID)
+}
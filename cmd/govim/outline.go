@@ -0,0 +1,292 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/govim/govim"
+	"github.com/govim/govim/cmd/govim/config"
+	"github.com/govim/govim/cmd/govim/internal/golang_org_x_tools_gopls/protocol"
+	"github.com/govim/govim/cmd/govim/internal/types"
+)
+
+// outline is the state of the sidebar opened by CommandOutline. The sidebar
+// is a tree view of the document symbols of a single buffer, which follows
+// the Go buffer the user is editing.
+type outline struct {
+	tv *treeView
+
+	// buf is the buffer whose symbols are shown
+	buf *types.Buffer
+
+	// ranges are the ranges of the symbols represented by the nodes of tv,
+	// used to find the node that contains the cursor
+	ranges map[*treeNode]protocol.Range
+
+	// cursor is the last known cursor position within buf
+	cursor *protocol.Position
+
+	// cancel cancels the ongoing gopls.DocumentSymbol request, if any
+	cancel context.CancelFunc
+}
+
+// toggleOutline opens the outline sidebar for the current buffer, or closes
+// it if it is already open.
+func (v *vimstate) toggleOutline(flags govim.CommandFlags, args ...string) error {
+	if o := v.openOutline(); o != nil {
+		v.closeOutline()
+		return nil
+	}
+	cb, pos, err := v.bufCursorPos()
+	if err != nil {
+		return fmt.Errorf("failed to determine cursor position: %v", err)
+	}
+	syms, err := v.documentSymbols(context.Background(), cb)
+	if err != nil {
+		return err
+	}
+	o := &outline{
+		buf:    cb,
+		ranges: make(map[*treeNode]protocol.Range),
+	}
+	roots := o.nodes(syms, nil)
+	tv, err := v.openTreeView(flags.Mods, "vertical topleft 40split", "outline", v.outlineTitle(cb), roots)
+	if err != nil {
+		return err
+	}
+	o.tv = tv
+	v.outline = o
+
+	// The sidebar is opened alongside the buffer being edited, so return to it
+	v.ChannelCall("win_gotoid", tv.originWinID)
+	p := pos.ToPosition()
+	o.cursor = &p
+	v.highlightOutlineCursor()
+	return nil
+}
+
+// openOutline returns the outline sidebar if it is still open, i.e. it has not
+// been closed via its tree view mappings or by wiping out its buffer.
+func (v *vimstate) openOutline() *outline {
+	o := v.outline
+	if o == nil {
+		return nil
+	}
+	if v.treeViews[o.tv.bufNr] != o.tv || v.ParseInt(v.ChannelCall("bufexists", o.tv.bufNr)) == 0 {
+		if o.cancel != nil {
+			o.cancel()
+		}
+		delete(v.treeViews, o.tv.bufNr)
+		v.outline = nil
+		return nil
+	}
+	return o
+}
+
+func (v *vimstate) closeOutline() {
+	o := v.outline
+	if o.cancel != nil {
+		o.cancel()
+	}
+	delete(v.treeViews, o.tv.bufNr)
+	v.outline = nil
+	v.ChannelExf("bwipeout %d", o.tv.bufNr)
+}
+
+func (v *vimstate) documentSymbols(ctx context.Context, b *types.Buffer) ([]protocol.DocumentSymbol, error) {
+	res, err := v.server.DocumentSymbol(ctx, &protocol.DocumentSymbolParams{
+		TextDocument: b.ToTextDocumentIdentifier(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to call gopls.DocumentSymbol: %v", err)
+	}
+	// The result is a union of []DocumentSymbol and []SymbolInformation; the
+	// former is returned because govim announces support for hierarchical
+	// document symbols.
+	byts, err := json.Marshal(res)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal document symbols: %v", err)
+	}
+	var syms []protocol.DocumentSymbol
+	if err := json.Unmarshal(byts, &syms); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal document symbols: %v", err)
+	}
+	return syms, nil
+}
+
+// nodes converts syms to tree nodes, recording the range of each node. Nodes
+// are expanded unless the node with the same path in the previous tree,
+// represented by collapsed, was collapsed.
+func (o *outline) nodes(syms []protocol.DocumentSymbol, collapsed map[string]bool) []*treeNode {
+	var conv func(syms []protocol.DocumentSymbol, parent string) []*treeNode
+	conv = func(syms []protocol.DocumentSymbol, parent string) []*treeNode {
+		var res []*treeNode
+		for _, s := range syms {
+			n := &treeNode{
+				text: fmt.Sprintf("%s  %v", s.Name, s.Kind),
+				locs: []protocol.Location{{
+					URI:   protocol.DocumentURI(o.buf.URI()),
+					Range: s.SelectionRange,
+				}},
+				fetched: true,
+			}
+			path := outlinePath(parent, n)
+			n.children = conv(s.Children, path)
+			n.expanded = !collapsed[path]
+			o.ranges[n] = s.Range
+			res = append(res, n)
+		}
+		return res
+	}
+	return conv(syms, "")
+}
+
+// collapsed returns the set of paths of the collapsed nodes in the tree, such
+// that their state can be retained when the tree is refreshed.
+func (o *outline) collapsed() map[string]bool {
+	res := make(map[string]bool)
+	var walk func(nodes []*treeNode, parent string)
+	walk = func(nodes []*treeNode, parent string) {
+		for _, n := range nodes {
+			path := outlinePath(parent, n)
+			if !n.expanded {
+				res[path] = true
+			}
+			walk(n.children, path)
+		}
+	}
+	walk(o.tv.roots, "")
+	return res
+}
+
+func outlinePath(parent string, n *treeNode) string {
+	return parent + "\x00" + n.text
+}
+
+func (v *vimstate) outlineTitle(b *types.Buffer) string {
+	return fmt.Sprintf("Outline of %s", v.relPath(b.Name))
+}
+
+// updateOutline refreshes the outline sidebar in the background if it shows
+// the symbols of b.
+func (v *vimstate) updateOutline(b *types.Buffer) {
+	o := v.outline
+	if o == nil || o.buf != b {
+		return
+	}
+	if o.cancel != nil {
+		o.cancel()
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	o.cancel = cancel
+	version := b.Version
+	v.tomb.Go(func() error {
+		syms, err := v.documentSymbols(ctx, b)
+		select {
+		case <-ctx.Done():
+			return nil
+		default:
+		}
+		v.govimplugin.Schedule(func(govim.Govim) error {
+			// The buffer has changed again, or the outline has been closed or
+			// switched to another buffer
+			select {
+			case <-ctx.Done():
+				return nil
+			default:
+			}
+			o.cancel = nil
+			if err != nil {
+				return err
+			}
+			if v.outline != o || o.buf != b || b.Version != version || v.openOutline() == nil {
+				return nil
+			}
+			collapsed := o.collapsed()
+			o.ranges = make(map[*treeNode]protocol.Range)
+			o.tv.roots = o.nodes(syms, collapsed)
+			o.tv.title = v.outlineTitle(b)
+			v.renderTreeView(o.tv)
+			v.highlightOutlineCursor()
+			return nil
+		})
+		return nil
+	})
+}
+
+// updateOutlineCursor is called when the user is idle, with the cursor at pos.
+// The entry that contains the cursor is highlighted in the outline sidebar. If
+// the cursor is in a different Go buffer, the sidebar switches to that buffer.
+func (v *vimstate) updateOutlineCursor(pos types.CursorPosition) {
+	o := v.outline
+	if o == nil || pos.BufNr == o.tv.bufNr || pos.Point == nil {
+		return
+	}
+	b, ok := v.buffers[pos.BufNr]
+	if !ok || !strings.HasSuffix(b.Name, ".go") {
+		return
+	}
+	if o = v.openOutline(); o == nil {
+		return
+	}
+	o.tv.originWinID = pos.WinID
+	p := pos.ToPosition()
+	o.cursor = &p
+	if o.buf != b {
+		o.buf = b
+		v.updateOutline(b)
+		return
+	}
+	v.highlightOutlineCursor()
+}
+
+// highlightOutlineCursor highlights the innermost visible entry of the
+// outline sidebar that contains the last known cursor position.
+func (v *vimstate) highlightOutlineCursor() {
+	o := v.outline
+	line := 0
+	if o.cursor != nil {
+		// Children are rendered after their parent, hence the last entry
+		// containing the cursor is the innermost one
+		for i, n := range o.tv.lines {
+			if r, ok := o.ranges[n]; ok && rangeContains(r, *o.cursor) {
+				line = i + 2
+			}
+		}
+	}
+	v.BatchStart()
+	defer v.BatchCancelIfNotEnded()
+	v.BatchChannelCall("prop_remove", struct {
+		Type  string `json:"type"`
+		BufNr int    `json:"bufnr"`
+		All   int    `json:"all"`
+	}{string(config.HighlightOutlineCurrent), o.tv.bufNr, 1})
+	if line > 0 {
+		v.BatchChannelCall("getbufline", o.tv.bufNr, line)
+	}
+	res := v.MustBatchEnd()
+	if line == 0 {
+		return
+	}
+	var text []string
+	v.Parse(res[1], &text)
+	if len(text) != 1 || text[0] == "" {
+		return
+	}
+	v.ChannelCall("prop_add", line, 1, propAddDict{string(config.HighlightOutlineCurrent), 0, line, len(text[0]) + 1, o.tv.bufNr})
+}
+
+func rangeContains(r protocol.Range, p protocol.Position) bool {
+	if p.Line < r.Start.Line || p.Line > r.End.Line {
+		return false
+	}
+	if p.Line == r.Start.Line && p.Character < r.Start.Character {
+		return false
+	}
+	if p.Line == r.End.Line && p.Character > r.End.Character {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,280 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/govim/govim"
+	"github.com/govim/govim/cmd/govim/internal/golang_org_x_tools_gopls/protocol"
+	"github.com/govim/govim/cmd/govim/internal/types"
+)
+
+// diagnosticReport is a diagnostic report of a document, as returned by
+// gopls.Diagnostic and gopls.DiagnosticWorkspace. The protocol defines
+// separate types for full and unchanged reports, which are distinguished by
+// Kind; this type holds the fields of both.
+type diagnosticReport struct {
+	Kind     protocol.DocumentDiagnosticReportKind `json:"kind"`
+	ResultID string                                `json:"resultId"`
+	Items    []protocol.Diagnostic                 `json:"items"`
+
+	// URI is the document reported on in a workspace report
+	URI protocol.DocumentURI `json:"uri"`
+
+	// RelatedDocuments are the reports of other documents, e.g. those
+	// affected by a change to the document reported on
+	RelatedDocuments map[protocol.DocumentURI]*diagnosticReport `json:"relatedDocuments"`
+}
+
+// setDiagnosticProvider enables pull diagnostics according to the diagnostic
+// options advertised by gopls, if ExperimentalPullDiagnostics is set. opts is
+// nil when gopls does not provide pull diagnostics, in which case govim uses
+// the diagnostics published by gopls.
+func (v *vimstate) setDiagnosticProvider(opts *protocol.Or_ServerCapabilities_diagnosticProvider) error {
+	if v.config.ExperimentalPullDiagnostics == nil || !*v.config.ExperimentalPullDiagnostics {
+		return nil
+	}
+	if opts == nil || opts.Value == nil {
+		v.Logf("gopls does not support pull diagnostics; using published diagnostics")
+		return nil
+	}
+	// The provider is either DiagnosticOptions or DiagnosticRegistrationOptions,
+	// the latter of which embeds the former
+	byts, err := json.Marshal(opts.Value)
+	if err != nil {
+		return fmt.Errorf("failed to marshal diagnostic options: %v", err)
+	}
+	var provider protocol.DiagnosticOptions
+	if err := json.Unmarshal(byts, &provider); err != nil {
+		return fmt.Errorf("failed to unmarshal diagnostic options: %v", err)
+	}
+	v.diagnosticsChangedLock.Lock()
+	v.pullDiagnostics = &provider
+	v.diagnosticsChangedLock.Unlock()
+	return nil
+}
+
+// diagnosticOptions returns the diagnostic options of gopls if diagnostics
+// are pulled, or nil if they are published.
+func (v *vimstate) diagnosticOptions() *protocol.DiagnosticOptions {
+	v.diagnosticsChangedLock.Lock()
+	defer v.diagnosticsChangedLock.Unlock()
+	return v.pullDiagnostics
+}
+
+// pullBufferDiagnostics requests the diagnostics of b in the background. Any
+// ongoing request for b is cancelled so that only the latest response is
+// used.
+func (v *vimstate) pullBufferDiagnostics(b *types.Buffer) {
+	opts := v.diagnosticOptions()
+	if opts == nil || !b.Loaded || !strings.HasSuffix(b.Name, ".go") {
+		return
+	}
+	v.cancelPullBufferDiagnostics(b.Num)
+	ctx, cancel := context.WithCancel(context.Background())
+	v.cancelPullDiagnostics[b.Num] = cancel
+	uri := b.URI()
+	params := &protocol.DocumentDiagnosticParams{
+		TextDocument:     b.ToTextDocumentIdentifier(),
+		Identifier:       opts.Identifier,
+		PreviousResultID: v.diagnosticResultIDs[uri],
+	}
+	v.tomb.Go(func() error {
+		res, err := v.server.Diagnostic(ctx, params)
+		select {
+		case <-ctx.Done():
+			return nil
+		default:
+		}
+		v.govimplugin.Schedule(func(govim.Govim) error {
+			// The buffer has changed again, or has been unloaded
+			select {
+			case <-ctx.Done():
+				return nil
+			default:
+			}
+			delete(v.cancelPullDiagnostics, b.Num)
+			if err != nil {
+				return fmt.Errorf("failed to call gopls.Diagnostic: %v", err)
+			}
+			var report *diagnosticReport
+			if res != nil {
+				if err := convertDiagnosticReport(res.Value, &report); err != nil {
+					return err
+				}
+			}
+			return v.pulledDiagnosticsChanged(v.storeDiagnosticReport(uri, report))
+		})
+		return nil
+	})
+}
+
+// pullWorkspaceDiagnostics requests the diagnostics of the workspace in the
+// background, passing the result IDs of the documents reported on so far.
+// Any ongoing workspace request is cancelled.
+func (v *vimstate) pullWorkspaceDiagnostics(opts *protocol.DiagnosticOptions) {
+	if v.cancelPullWorkspaceDiagnostics != nil {
+		v.cancelPullWorkspaceDiagnostics()
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	v.cancelPullWorkspaceDiagnostics = cancel
+	params := &protocol.WorkspaceDiagnosticParams{
+		Identifier:        opts.Identifier,
+		PreviousResultIds: []protocol.PreviousResultID{},
+	}
+	for uri, id := range v.diagnosticResultIDs {
+		params.PreviousResultIds = append(params.PreviousResultIds, protocol.PreviousResultID{
+			URI:   uri,
+			Value: id,
+		})
+	}
+	v.tomb.Go(func() error {
+		res, err := v.server.DiagnosticWorkspace(ctx, params)
+		select {
+		case <-ctx.Done():
+			return nil
+		default:
+		}
+		v.govimplugin.Schedule(func(govim.Govim) error {
+			select {
+			case <-ctx.Done():
+				return nil
+			default:
+			}
+			v.cancelPullWorkspaceDiagnostics = nil
+			if err != nil {
+				return fmt.Errorf("failed to call gopls.DiagnosticWorkspace: %v", err)
+			}
+			if res == nil {
+				return nil
+			}
+			changed := false
+			for _, item := range res.Items {
+				var report *diagnosticReport
+				if err := convertDiagnosticReport(item.Value, &report); err != nil {
+					return err
+				}
+				if report != nil && v.storeDiagnosticReport(report.URI, report) {
+					changed = true
+				}
+			}
+			return v.pulledDiagnosticsChanged(changed)
+		})
+		return nil
+	})
+}
+
+// refreshPullDiagnostics requests the diagnostics of the workspace, if gopls
+// supports workspace diagnostics, or else those of all loaded buffers.
+func (v *vimstate) refreshPullDiagnostics() error {
+	opts := v.diagnosticOptions()
+	if opts == nil {
+		return nil
+	}
+	if opts.WorkspaceDiagnostics {
+		v.pullWorkspaceDiagnostics(opts)
+		return nil
+	}
+	for _, b := range v.buffers {
+		v.pullBufferDiagnostics(b)
+	}
+	return nil
+}
+
+// storeDiagnosticReport records the diagnostics reported for uri, along with
+// those of any related documents, and returns whether any of them changed.
+// An unchanged report keeps the diagnostics last reported for uri.
+func (v *vimstate) storeDiagnosticReport(uri protocol.DocumentURI, report *diagnosticReport) bool {
+	if report == nil {
+		return false
+	}
+	changed := false
+	for ruri, r := range report.RelatedDocuments {
+		if v.storeDiagnosticReport(ruri, r) {
+			changed = true
+		}
+	}
+	if report.ResultID != "" {
+		v.diagnosticResultIDs[uri] = report.ResultID
+	} else {
+		delete(v.diagnosticResultIDs, uri)
+	}
+	if report.Kind == protocol.DiagnosticUnchanged {
+		return changed
+	}
+
+	v.diagnosticsChangedLock.Lock()
+	defer v.diagnosticsChangedLock.Unlock()
+	curr, ok := v.rawDiagnostics[uri]
+	if !ok {
+		if len(report.Items) == 0 {
+			return changed
+		}
+	} else if (len(curr.Diagnostics) == 0 && len(report.Items) == 0) || reflect.DeepEqual(curr.Diagnostics, report.Items) {
+		return changed
+	}
+	v.rawDiagnostics[uri] = &protocol.PublishDiagnosticsParams{
+		URI:         uri,
+		Diagnostics: report.Items,
+	}
+	v.diagnosticsChanged = true
+	return true
+}
+
+// pulledDiagnosticsChanged updates the quickfix window, signs and highlights
+// if pulled diagnostics have changed, in the same way as for published
+// diagnostics.
+func (v *vimstate) pulledDiagnosticsChanged(changed bool) error {
+	if !changed || v.userBusy {
+		return nil
+	}
+	return v.handleDiagnosticsChanged()
+}
+
+// cancelPullBufferDiagnostics cancels any ongoing request for the diagnostics
+// of the buffer bufnr.
+func (v *vimstate) cancelPullBufferDiagnostics(bufnr int) {
+	if cancel, ok := v.cancelPullDiagnostics[bufnr]; ok {
+		cancel()
+		delete(v.cancelPullDiagnostics, bufnr)
+	}
+}
+
+// forgetPulledDiagnostics cancels any ongoing request for the diagnostics of
+// b and forgets those last reported, because b has been deleted. Unlike
+// published diagnostics, nothing would otherwise keep them up to date.
+func (v *vimstate) forgetPulledDiagnostics(b *types.Buffer) error {
+	if v.diagnosticOptions() == nil {
+		return nil
+	}
+	v.cancelPullBufferDiagnostics(b.Num)
+	uri := b.URI()
+	delete(v.diagnosticResultIDs, uri)
+	v.diagnosticsChangedLock.Lock()
+	_, ok := v.rawDiagnostics[uri]
+	delete(v.rawDiagnostics, uri)
+	if ok {
+		v.diagnosticsChanged = true
+	}
+	v.diagnosticsChangedLock.Unlock()
+	return v.pulledDiagnosticsChanged(ok)
+}
+
+// convertDiagnosticReport converts a member of one of the protocol's
+// diagnostic report unions to a diagnosticReport.
+func convertDiagnosticReport(value interface{}, report **diagnosticReport) error {
+	if value == nil {
+		return nil
+	}
+	byts, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("failed to marshal diagnostic report: %v", err)
+	}
+	if err := json.Unmarshal(byts, report); err != nil {
+		return fmt.Errorf("failed to unmarshal diagnostic report: %v", err)
+	}
+	return nil
+}
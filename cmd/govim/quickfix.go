@@ -5,6 +5,7 @@ import (
 	"path/filepath"
 
 	"github.com/govim/govim"
+	"github.com/govim/govim/cmd/govim/internal/types"
 )
 
 const (
@@ -46,20 +47,9 @@ func (v *vimstate) updateQuickfixWithDiagnostics(force bool) error {
 	// must be non-nil
 	fixes := []quickfixEntry{}
 	for _, d := range *diags {
-		// make fn relative for reporting purposes
-		fn, err := filepath.Rel(v.workingDirectory, d.Filename)
-		if err != nil {
-			v.Logf("redefineDiagnostics: failed to call filepath.Rel(%q, %q): %v", v.workingDirectory, fn, err)
-			continue
+		if f, ok := v.diagnosticQuickfixEntry(d); ok {
+			fixes = append(fixes, f)
 		}
-
-		fixes = append(fixes, quickfixEntry{
-			Filename: fn,
-			Lnum:     d.Range.Start.Line(),
-			Col:      d.Range.Start.Col(),
-			Text:     d.Text,
-			Buf:      d.Buf,
-		})
 	}
 
 	// Note: indexes are 1-based, hence 0 means "no index"
@@ -117,6 +107,23 @@ NewIndexSet:
 	return nil
 }
 
+// diagnosticQuickfixEntry returns the quickfix entry for d, with the filename
+// made relative to the working directory for reporting purposes.
+func (v *vimstate) diagnosticQuickfixEntry(d types.Diagnostic) (quickfixEntry, bool) {
+	fn, err := filepath.Rel(v.workingDirectory, d.Filename)
+	if err != nil {
+		v.Logf("redefineDiagnostics: failed to call filepath.Rel(%q, %q): %v", v.workingDirectory, d.Filename, err)
+		return quickfixEntry{}, false
+	}
+	return quickfixEntry{
+		Filename: fn,
+		Lnum:     d.Range.Start.Line(),
+		Col:      d.Range.Start.Col(),
+		Text:     d.Text,
+		Buf:      d.Buf,
+	}, true
+}
+
 // setQuickfixDiagnostics fills quickfix list with diagnostics, and set the title and index (if != 0).
 func (v *vimstate) setQuickfixDiagnostics(diags []quickfixEntry, index int) {
 	v.lastQuickFixDiagnostics = diags
@@ -129,4 +136,7 @@ func (v *vimstate) setQuickfixDiagnostics(diags []quickfixEntry, index int) {
 type qflistProps struct {
 	Idx   int    `json:"idx,omitempty"`
 	Title string `json:"title,omitempty"`
+	Size  int    `json:"size,omitempty"`
+
+	Items []quickfixEntry `json:"items,omitempty"`
 }
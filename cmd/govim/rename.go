@@ -3,30 +3,56 @@ package main
 import (
 	"context"
 	"fmt"
+	"os"
 	"sort"
+	"strings"
 
 	"github.com/govim/govim"
+	"github.com/govim/govim/cmd/govim/config"
 	"github.com/govim/govim/cmd/govim/internal/golang_org_x_tools_gopls/protocol"
 )
 
+// rename renames the identifier under the cursor, as validated by
+// gopls.PrepareRename. With a bang, a diff of the changes is previewed and
+// they are only applied once the user confirms them.
 func (v *vimstate) rename(flags govim.CommandFlags, args ...string) error {
 	b, pos, err := v.bufCursorPos()
 	if err != nil {
 		return fmt.Errorf("failed to get current position: %v", err)
 	}
+	textDoc := protocol.TextDocumentIdentifier{
+		URI: protocol.DocumentURI(b.URI()),
+	}
+	prep, err := v.server.PrepareRename(context.Background(), &protocol.PrepareRenameParams{
+		TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+			TextDocument: textDoc,
+			Position:     pos.ToPosition(),
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("cannot rename at cursor: %v", err)
+	}
+	if prep == nil {
+		return fmt.Errorf("cannot rename at cursor")
+	}
+	curr := prep.Placeholder
+	if curr == "" {
+		curr = v.ParseString(v.ChannelExprf(`expand("<cword>")`))
+	}
 	var renameTo string
 	if len(args) == 1 {
 		renameTo = args[0]
 	} else {
-		curr := v.ParseString(v.ChannelExprf(`expand("<cword>")`))
 		renameTo = v.ParseString(v.ChannelExprf(`input("govim: rename '%v' to: ", %q)`, curr, curr))
 	}
+	// An empty name is the user cancelling the prompt
+	if renameTo == "" || renameTo == curr {
+		return nil
+	}
 	params := &protocol.RenameParams{
-		TextDocument: protocol.TextDocumentIdentifier{
-			URI: protocol.DocumentURI(b.URI()),
-		},
-		Position: pos.ToPosition(),
-		NewName:  renameTo,
+		TextDocument: textDoc,
+		Position:     prep.Range.Start,
+		NewName:      renameTo,
 	}
 
 	res, err := v.server.Rename(context.Background(), params)
@@ -34,33 +60,79 @@ func (v *vimstate) rename(flags govim.CommandFlags, args ...string) error {
 		return fmt.Errorf("called to gopls.Rename failed: %v", err)
 	}
 
+	if flags.Bang != nil && *flags.Bang {
+		prompt := fmt.Sprintf("govim: rename '%v' to '%v'?", curr, renameTo)
+		ok, err := v.confirmEditPreview(flags.Mods, "rename", prompt, res.DocumentChanges)
+		if err != nil || !ok {
+			return err
+		}
+	}
 	return v.applyMultiBufTextedits(flags.Mods, res.DocumentChanges)
 }
 
+// applyMultiBufTextedits applies changes in order. Consecutive text document
+// edits are applied together, editing files that are not visible according to
+// the MultiFileEditStrategy config; files are created, renamed and deleted in
+// between.
 func (v *vimstate) applyMultiBufTextedits(splitMods govim.CommModList, changes []protocol.DocumentChange) error {
-	allChanges := changes
-	if len(allChanges) == 0 {
+	if len(changes) == 0 {
 		v.Logf("No changes to apply for rename")
 		return nil
 	}
-	// TODO: it feels like we need a new config variable for the strategy to use
-	// when making edits of this sort (to multiple files). It doesn't feel right
-	// to use the value of &switchbuf because there might be multiple changes
-	// (as opposed to jumping to a single definition/location). For now we
-	// hardcode a split.
+	var edits []protocol.TextDocumentEdit
+	var summary []quickfixEntry
+	for _, c := range changes {
+		if c.TextDocumentEdit != nil {
+			edits = append(edits, *c.TextDocumentEdit)
+			continue
+		}
+		s, err := v.applyTextDocumentEdits(splitMods, edits)
+		if err != nil {
+			return err
+		}
+		summary = append(summary, s...)
+		edits = nil
+		if err := v.applyResourceOperation(c); err != nil {
+			return err
+		}
+	}
+	s, err := v.applyTextDocumentEdits(splitMods, edits)
+	if err != nil {
+		return err
+	}
+	summary = append(summary, s...)
+	if v.config.MultiFileEditQuickfix != nil && *v.config.MultiFileEditQuickfix && len(summary) > 0 {
+		// A new list, rather than replacing the current one
+		v.ChannelCall("setqflist", []quickfixEntry{}, " ", qflistProps{Title: multiFileEditQuickfixTitle, Items: summary})
+	}
+	return nil
+}
+
+const multiFileEditQuickfixTitle = "govim: edits"
+
+func (v *vimstate) multiFileEditStrategy() config.MultiFileEditStrategy {
+	if v.config.MultiFileEditStrategy == nil {
+		return config.MultiFileEditStrategySplit
+	}
+	return *v.config.MultiFileEditStrategy
+}
+
+// applyTextDocumentEdits applies edits, and returns a quickfix entry for each
+// change made. Files that are not visible are edited according to the
+// MultiFileEditStrategy config.
+func (v *vimstate) applyTextDocumentEdits(splitMods govim.CommModList, edits []protocol.TextDocumentEdit) ([]quickfixEntry, error) {
+	if len(edits) == 0 {
+		return nil, nil
+	}
+	strategy := v.multiFileEditStrategy()
 	vp := v.Viewport()
 	bufNrs := make(map[string]int)
+	onDisk := make(map[string]bool)
 	var fps []string
 	uriMap := make(map[protocol.DocumentURI]protocol.TextDocumentEdit)
-	for _, c := range allChanges {
-		// TODO: protocol.DocumentChanges is a union that (currently) must have
-		// either TextDocumentEdit or RenameFile set. We should add support for
-		// renaming files as well.
-		if c.TextDocumentEdit == nil {
-			return fmt.Errorf("file renaming not supported")
-		}
-		uriMap[c.TextDocumentEdit.TextDocument.TextDocumentIdentifier.URI] = *c.TextDocumentEdit
-		fps = append(fps, string(c.TextDocumentEdit.TextDocument.TextDocumentIdentifier.URI))
+	for _, e := range edits {
+		uriMap[e.TextDocument.TextDocumentIdentifier.URI] = e
+		fps = append(fps, string(e.TextDocument.TextDocumentIdentifier.URI))
 	}
 	// So that we have reproducible behaviour
 	sort.Strings(fps)
@@ -69,25 +141,47 @@ func (v *vimstate) applyMultiBufTextedits(splitMods govim.CommModList, changes [
 		tf := protocol.DocumentURI(filepath).Path()
 		var bufinfo []struct {
 			BufNr   int   `json:"bufnr"`
+			Loaded  int   `json:"loaded"`
 			Windows []int `json:"windows"`
 		}
-		v.Parse(v.ChannelExprf(`map(getbufinfo(%q), {_, v -> filter(v, 'v:key == "bufnr" || v:key == "windows"')})`, tf), &bufinfo)
+		v.Parse(v.ChannelExprf(`map(getbufinfo(%q), {_, v -> filter(v, 'v:key == "bufnr" || v:key == "loaded" || v:key == "windows"')})`, tf), &bufinfo)
+		loaded := false
 		switch len(bufinfo) {
 		case 0:
 		case 1:
 			bufNrs[tf] = bufinfo[0].BufNr
+			loaded = bufinfo[0].Loaded != 0
 			if len(bufinfo[0].Windows) > 0 {
 				continue
 			}
 		default:
-			return fmt.Errorf("got back multiple buffers searching for %v", tf)
+			return nil, fmt.Errorf("got back multiple buffers searching for %v", tf)
+		}
+		switch strategy {
+		case config.MultiFileEditStrategyTab:
+			v.ChannelExf("%v tab split %v", splitMods, tf)
+			bufNrs[tf] = v.ParseInt(v.ChannelCall("bufnr", tf))
+		case config.MultiFileEditStrategyHidden, config.MultiFileEditStrategyDisk:
+			if loaded {
+				continue
+			}
+			if strategy == config.MultiFileEditStrategyDisk {
+				onDisk[tf] = true
+				continue
+			}
+			nr := v.ParseInt(v.ChannelCall("bufadd", tf))
+			v.ChannelCall("setbufvar", nr, "&buflisted", 1)
+			v.ChannelExf("silent call bufload(%d)", nr)
+			bufNrs[tf] = nr
+		default:
+			v.ChannelExf("%v split %v", splitMods, tf)
+			bufNrs[tf] = v.ParseInt(v.ChannelCall("bufnr", tf))
 		}
-		// Hard code split for now
-		v.ChannelExf("%v split %v", splitMods, tf)
-		bufNrs[tf] = v.ParseInt(v.ChannelCall("bufnr", tf))
 	}
 	v.ChannelCall("win_gotoid", vp.Current.WinID)
 
+	var summary []quickfixEntry
+	var changed []int
 	for _, filepath := range fps {
 		uri := protocol.DocumentURI(filepath)
 		tf := uri.Path()
@@ -95,20 +189,102 @@ func (v *vimstate) applyMultiBufTextedits(splitMods govim.CommModList, changes [
 		if len(changes.Edits) == 0 {
 			continue
 		}
+		textEdits := protocol.AsTextEdits(changes.Edits)
+		if onDisk[tf] {
+			lines, err := applyTextEditsOnDisk(uri, textEdits)
+			if err != nil {
+				return nil, err
+			}
+			summary = append(summary, editsSummary(tf, textEdits, lines)...)
+			continue
+		}
 		bufnr := bufNrs[tf]
 		b, ok := v.buffers[bufnr]
 		if !ok {
-			return fmt.Errorf("expected to have a buffer for %v; did not", tf)
+			return nil, fmt.Errorf("expected to have a buffer for %v; did not", tf)
 		}
 		// We previously verified the filepath above by doing the reverse
 		// lookup from filepath -> buffer, so just verify the version
 		ev := changes.TextDocument.Version
 		if ev > 0 && ev != b.Version {
-			return fmt.Errorf("edit for buffer %v (%v) was for version %v, current version is %v", tf, bufnr, ev, b.Version)
+			return nil, fmt.Errorf("edit for buffer %v (%v) was for version %v, current version is %v", tf, bufnr, ev, b.Version)
 		}
-		if err := v.applyProtocolTextEdits(b, protocol.AsTextEdits(changes.Edits)); err != nil {
-			return fmt.Errorf("failed to apply edits for %v: %v", tf, err)
+		if err := v.applyProtocolTextEdits(b, textEdits); err != nil {
+			return nil, fmt.Errorf("failed to apply edits for %v: %v", tf, err)
 		}
+		changed = append(changed, bufnr)
+		var lines []string
+		v.Parse(v.ChannelCall("getbufline", bufnr, 1, "$"), &lines)
+		summary = append(summary, editsSummary(tf, textEdits, lines)...)
 	}
-	return nil
+	if v.config.MultiFileEditAutoSave != nil && *v.config.MultiFileEditAutoSave {
+		for _, bufnr := range changed {
+			v.bufExecute(bufnr, "silent write")
+		}
+	}
+	return summary, nil
+}
+
+// applyTextEditsOnDisk applies edits to the file of uri, which is not loaded
+// in a buffer, and returns the lines of the changed file.
+func applyTextEditsOnDisk(uri protocol.DocumentURI, edits []protocol.TextEdit) ([]string, error) {
+	fn := uri.Path()
+	fi, err := os.Stat(fn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat %v: %v", fn, err)
+	}
+	before, err := os.ReadFile(fn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %v: %v", fn, err)
+	}
+	after, _, err := protocol.ApplyEdits(protocol.NewMapper(uri, before), edits)
+	if err != nil {
+		return nil, fmt.Errorf("failed to apply edits to %v: %v", fn, err)
+	}
+	if err := os.WriteFile(fn, after, fi.Mode()); err != nil {
+		return nil, fmt.Errorf("failed to write %v: %v", fn, err)
+	}
+	return strings.Split(string(after), "\n"), nil
+}
+
+// editsSummary returns a quickfix entry for each of edits to the file fn,
+// positioned at the start of its new text within lines, the lines of the
+// file once all edits have been applied.
+func editsSummary(fn string, edits []protocol.TextEdit, lines []string) []quickfixEntry {
+	edits = append([]protocol.TextEdit(nil), edits...)
+	sortEdits(edits)
+	var res []quickfixEntry
+	// delta is the number of lines added by preceding edits
+	delta := 0
+	for _, e := range edits {
+		line := int(e.Range.Start.Line) + delta
+		delta += strings.Count(e.NewText, "\n") - int(e.Range.End.Line-e.Range.Start.Line)
+		var text string
+		if line < len(lines) {
+			text = strings.TrimSpace(lines[line])
+		}
+		res = append(res, quickfixEntry{
+			Filename: fn,
+			Lnum:     line + 1,
+			Col:      int(e.Range.Start.Character) + 1,
+			Text:     text,
+		})
+	}
+	return res
+}
+
+// bufExecute executes the Ex command cmd in a window showing the buffer
+// bufnr. If the buffer is hidden it is shown in a new window for the duration.
+func (v *vimstate) bufExecute(bufnr int, cmd string) {
+	var wins []int
+	v.Parse(v.ChannelCall("win_findbuf", bufnr), &wins)
+	if len(wins) > 0 {
+		v.ChannelCall("win_execute", wins[0], cmd)
+		return
+	}
+	vp := v.Viewport()
+	v.ChannelExf("noautocmd keepalt sbuffer %d", bufnr)
+	v.ChannelEx(cmd)
+	v.ChannelEx("noautocmd close")
+	v.ChannelCall("win_gotoid", vp.Current.WinID)
 }
@@ -0,0 +1,295 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/govim/govim/cmd/govim/internal/golang_org_x_tools_gopls/protocol"
+	"github.com/kr/pretty"
+)
+
+// applyResourceOperation creates, renames or deletes the file or directory of
+// c, a document change that is not a text document edit.
+func (v *vimstate) applyResourceOperation(c protocol.DocumentChange) error {
+	switch {
+	case c.CreateFile != nil:
+		return v.createFile(c.CreateFile)
+	case c.RenameFile != nil:
+		return v.renameFile(c.RenameFile)
+	case c.DeleteFile != nil:
+		return v.deleteFile(c.DeleteFile)
+	}
+	return fmt.Errorf("unsupported document change: %v", pretty.Sprint(c))
+}
+
+func (v *vimstate) createFile(op *protocol.CreateFile) error {
+	path := op.URI.Path()
+	opts := op.Options
+	if opts == nil {
+		opts = &protocol.CreateFileOptions{}
+	}
+	if _, err := os.Stat(path); err == nil {
+		// Overwrite wins over IgnoreIfExists
+		if !opts.Overwrite {
+			if opts.IgnoreIfExists {
+				return nil
+			}
+			return fmt.Errorf("failed to create %v: file already exists", path)
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0777); err != nil {
+		return fmt.Errorf("failed to create directory for %v: %v", path, err)
+	}
+	if err := os.WriteFile(path, nil, 0666); err != nil {
+		return fmt.Errorf("failed to create %v: %v", path, err)
+	}
+	if o := v.fileOperations; o != nil && v.fileOperationMatches(o.DidCreate, path, false) {
+		params := &protocol.CreateFilesParams{
+			Files: []protocol.FileCreate{{URI: string(op.URI)}},
+		}
+		if err := v.server.DidCreateFiles(context.Background(), params); err != nil {
+			return fmt.Errorf("failed to call gopls.DidCreateFiles: %v", err)
+		}
+	}
+	return nil
+}
+
+func (v *vimstate) renameFile(op *protocol.RenameFile) error {
+	oldPath := op.OldURI.Path()
+	newPath := op.NewURI.Path()
+	opts := op.Options
+	if opts == nil {
+		opts = &protocol.RenameFileOptions{}
+	}
+	fi, err := os.Stat(oldPath)
+	if err != nil {
+		return fmt.Errorf("failed to rename %v: %v", oldPath, err)
+	}
+	isDir := fi.IsDir()
+	if _, err := os.Stat(newPath); err == nil && !opts.Overwrite {
+		if opts.IgnoreIfExists {
+			return nil
+		}
+		return fmt.Errorf("failed to rename %v to %v: file already exists", oldPath, newPath)
+	}
+
+	files := []protocol.FileRename{{OldURI: string(op.OldURI), NewURI: string(op.NewURI)}}
+	if o := v.fileOperations; o != nil && v.fileOperationMatches(o.WillRename, oldPath, isDir) {
+		edit, err := v.server.WillRenameFiles(context.Background(), &protocol.RenameFilesParams{Files: files})
+		if err != nil {
+			return fmt.Errorf("failed to call gopls.WillRenameFiles: %v", err)
+		}
+		if edit != nil && len(edit.DocumentChanges) > 0 {
+			if err := v.applyMultiBufTextedits(nil, edit.DocumentChanges); err != nil {
+				return fmt.Errorf("failed to apply edits before renaming %v: %v", oldPath, err)
+			}
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(newPath), 0777); err != nil {
+		return fmt.Errorf("failed to create directory for %v: %v", newPath, err)
+	}
+	if err := os.Rename(oldPath, newPath); err != nil {
+		return fmt.Errorf("failed to rename %v to %v: %v", oldPath, newPath, err)
+	}
+	for _, b := range v.vimBuffersUnder(oldPath) {
+		if err := v.renameBuffer(b, newPath+strings.TrimPrefix(b.Name, oldPath)); err != nil {
+			return err
+		}
+	}
+
+	if o := v.fileOperations; o != nil && v.fileOperationMatches(o.DidRename, newPath, isDir) {
+		if err := v.server.DidRenameFiles(context.Background(), &protocol.RenameFilesParams{Files: files}); err != nil {
+			return fmt.Errorf("failed to call gopls.DidRenameFiles: %v", err)
+		}
+	}
+	return nil
+}
+
+func (v *vimstate) deleteFile(op *protocol.DeleteFile) error {
+	path := op.URI.Path()
+	opts := op.Options
+	if opts == nil {
+		opts = &protocol.DeleteFileOptions{}
+	}
+	fi, err := os.Stat(path)
+	if err != nil {
+		if os.IsNotExist(err) && opts.IgnoreIfNotExists {
+			return nil
+		}
+		return fmt.Errorf("failed to delete %v: %v", path, err)
+	}
+	isDir := fi.IsDir()
+	// Wiping out a buffer of a Go file closes it in gopls via the BufWipeout
+	// autocommand
+	for _, b := range v.vimBuffersUnder(path) {
+		v.ChannelExf("bwipeout! %d", b.Num)
+	}
+	if isDir && !opts.Recursive {
+		if err := os.Remove(path); err != nil {
+			return fmt.Errorf("failed to delete %v: %v", path, err)
+		}
+	} else if err := os.RemoveAll(path); err != nil {
+		return fmt.Errorf("failed to delete %v: %v", path, err)
+	}
+	if o := v.fileOperations; o != nil && v.fileOperationMatches(o.DidDelete, path, isDir) {
+		params := &protocol.DeleteFilesParams{
+			Files: []protocol.FileDelete{{URI: string(op.URI)}},
+		}
+		if err := v.server.DidDeleteFiles(context.Background(), params); err != nil {
+			return fmt.Errorf("failed to call gopls.DidDeleteFiles: %v", err)
+		}
+	}
+	return nil
+}
+
+// vimBuffer is a buffer in Vim, which is not necessarily tracked by govim
+type vimBuffer struct {
+	Num    int    `json:"bufnr"`
+	Name   string `json:"name"`
+	Loaded int    `json:"loaded"`
+}
+
+// vimBuffersUnder returns the Vim buffers of the file path, or of files in
+// the directory path.
+func (v *vimstate) vimBuffersUnder(path string) []vimBuffer {
+	var all, res []vimBuffer
+	v.Parse(v.ChannelExpr(`map(getbufinfo(), {_, v -> {"bufnr": v.bufnr, "name": v.name, "loaded": v.loaded}})`), &all)
+	for _, b := range all {
+		if b.Name == path || strings.HasPrefix(b.Name, path+string(filepath.Separator)) {
+			res = append(res, b)
+		}
+	}
+	return res
+}
+
+// renameBuffer points the Vim buffer b at the file newName, to which its
+// file has been renamed. If govim tracks the buffer, gopls is told that the
+// document of the old file was closed, and that of the new file opened.
+func (v *vimstate) renameBuffer(b vimBuffer, newName string) error {
+	if b.Loaded == 0 {
+		// An unloaded buffer has no contents to keep, so is replaced by a
+		// buffer of the new file
+		v.ChannelExf("bwipeout %d", b.Num)
+		v.ChannelCall("bufadd", newName)
+		return nil
+	}
+	tracked, ok := v.buffers[b.Num]
+	if ok {
+		params := &protocol.DidCloseTextDocumentParams{
+			TextDocument: tracked.ToTextDocumentIdentifier(),
+		}
+		if err := v.server.DidClose(context.Background(), params); err != nil {
+			return fmt.Errorf("failed to call gopls.DidClose on %v: %v", tracked.Name, err)
+		}
+	}
+
+	modified := v.ParseInt(v.ChannelCall("getbufvar", b.Num, "&modified")) != 0
+	name := v.ParseString(v.ChannelCall("fnameescape", newName))
+	v.bufExecute(b.Num, "keepalt file "+name)
+	// :file also creates an unlisted buffer of the old name, which is of no
+	// use now that the file does not exist
+	var stale []int
+	v.Parse(v.ChannelExprf(`map(filter(getbufinfo(), {_, v -> v.name == %q}), {_, v -> v.bufnr})`, b.Name), &stale)
+	for _, nr := range stale {
+		v.ChannelExf("bwipeout %d", nr)
+	}
+
+	if ok {
+		tracked.Name = newName
+		tracked.Version = 1
+		if err := v.handleBufferEvent(tracked); err != nil {
+			return err
+		}
+	}
+
+	// :file marks the buffer as not edited, such that writing it requires a
+	// bang. The contents of an unmodified buffer are those of the renamed
+	// file, so writing it clears that without changing the file. Changes
+	// that have not been written are left for the user to write.
+	if modified {
+		v.ChannelExf("echom %q", fmt.Sprintf("govim: %v has unsaved changes; use :w! to write it", newName))
+		return nil
+	}
+	v.bufExecute(b.Num, "silent write!")
+	return nil
+}
+
+// fileOperationMatches returns true if the file or directory path matches
+// one of the filters with which gopls registered interest in a file
+// operation.
+func (v *vimstate) fileOperationMatches(reg *protocol.FileOperationRegistrationOptions, path string, isDir bool) bool {
+	if reg == nil {
+		return false
+	}
+	for _, f := range reg.Filters {
+		if f.Scheme != "" && f.Scheme != "file" {
+			continue
+		}
+		if m := f.Pattern.Matches; m != nil {
+			if (*m == protocol.FilePattern && isDir) || (*m == protocol.FolderPattern && !isDir) {
+				continue
+			}
+		}
+		ignoreCase := f.Pattern.Options != nil && f.Pattern.Options.IgnoreCase
+		re, err := globRegexp(f.Pattern.Glob, ignoreCase)
+		if err != nil {
+			v.Logf("failed to parse file operation glob %q: %v", f.Pattern.Glob, err)
+			continue
+		}
+		if re.MatchString(filepath.ToSlash(path)) {
+			return true
+		}
+	}
+	return false
+}
+
+// globRegexp converts an LSP glob pattern to a regular expression: * and ?
+// match within a path segment, ** matches any number of path segments, {a,b}
+// matches either alternative and [...] matches a range of characters.
+func globRegexp(glob string, ignoreCase bool) (*regexp.Regexp, error) {
+	var sb strings.Builder
+	if ignoreCase {
+		sb.WriteString("(?i)")
+	}
+	sb.WriteString("^")
+	inGroup := false
+	for i := 0; i < len(glob); i++ {
+		switch c := glob[i]; {
+		case strings.HasPrefix(glob[i:], "**/"):
+			sb.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "**"):
+			sb.WriteString(".*")
+			i++
+		case c == '*':
+			sb.WriteString("[^/]*")
+		case c == '?':
+			sb.WriteString("[^/]")
+		case c == '{':
+			inGroup = true
+			sb.WriteString("(?:")
+		case c == '}' && inGroup:
+			inGroup = false
+			sb.WriteString(")")
+		case c == ',' && inGroup:
+			sb.WriteString("|")
+		case c == '[' && strings.IndexByte(glob[i:], ']') > 1:
+			j := i + strings.IndexByte(glob[i:], ']')
+			class := glob[i+1 : j]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			sb.WriteString("[" + class + "]")
+			i = j
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	sb.WriteString("$")
+	return regexp.Compile(sb.String())
+}
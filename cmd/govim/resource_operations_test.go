@@ -0,0 +1,48 @@
+package main
+
+import "testing"
+
+var globRegexpTests = []struct {
+	glob       string
+	ignoreCase bool
+	path       string
+	match      bool
+}{
+	{"**/*.go", false, "/a/b/c.go", true},
+	{"**/*.go", false, "c.go", true},
+	{"**/*.go", false, "/a/b/c.gox", false},
+	{"*.go", false, "c.go", true},
+	{"*.go", false, "/a/c.go", false},
+	{"**", false, "/a/b/c", true},
+	{"/a/**/b.go", false, "/a/b.go", true},
+	{"/a/**/b.go", false, "/a/x/y/b.go", true},
+	{"/a/**/b.go", false, "/x/b.go", false},
+	{"/a/{b,c}/*.go", false, "/a/b/x.go", true},
+	{"/a/{b,c}/*.go", false, "/a/c/x.go", true},
+	{"/a/{b,c}/*.go", false, "/a/d/x.go", false},
+	{"/a/?.go", false, "/a/x.go", true},
+	{"/a/?.go", false, "/a/xy.go", false},
+	{"/a/?.go", false, "/a//.go", false},
+	{"/a/[0-9].txt", false, "/a/5.txt", true},
+	{"/a/[0-9].txt", false, "/a/x.txt", false},
+	{"/a/[!0-9].txt", false, "/a/x.txt", true},
+	{"/a/[!0-9].txt", false, "/a/5.txt", false},
+	{"/a/b+c.go", false, "/a/b+c.go", true},
+	{"/a/b+c.go", false, "/a/bbc.go", false},
+	{"/a/b.go", false, "/a/bxgo", false},
+	{"**/*.GO", false, "/a/b.go", false},
+	{"**/*.GO", true, "/a/b.go", true},
+}
+
+func TestGlobRegexp(t *testing.T) {
+	for _, tt := range globRegexpTests {
+		re, err := globRegexp(tt.glob, tt.ignoreCase)
+		if err != nil {
+			t.Errorf("globRegexp(%q, %v) failed: %v", tt.glob, tt.ignoreCase, err)
+			continue
+		}
+		if got := re.MatchString(tt.path); got != tt.match {
+			t.Errorf("globRegexp(%q, %v) (%v) matching %q = %v, want %v", tt.glob, tt.ignoreCase, re, tt.path, got, tt.match)
+		}
+	}
+}
@@ -0,0 +1,497 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/govim/govim"
+	"github.com/govim/govim/cmd/govim/config"
+	"github.com/govim/govim/cmd/govim/internal/golang_org_x_tools_gopls/protocol"
+	"github.com/govim/govim/cmd/govim/internal/types"
+)
+
+// semanticTokenTypes are the semantic token types for which govim defines a
+// highlight group, along with the group each links to by default. An empty
+// link means the group is cleared by default.
+var semanticTokenTypes = []struct {
+	name protocol.SemanticTokenTypes
+	link string
+}{
+	{protocol.NamespaceType, "Include"},
+	{protocol.TypeType, "Type"},
+	{protocol.ClassType, "Type"},
+	{protocol.EnumType, "Type"},
+	{protocol.InterfaceType, "Type"},
+	{protocol.StructType, "Type"},
+	{protocol.TypeParameterType, "Special"},
+	{protocol.ParameterType, ""},
+	{protocol.VariableType, ""},
+	{protocol.PropertyType, ""},
+	{protocol.EnumMemberType, "Constant"},
+	{protocol.EventType, ""},
+	{protocol.FunctionType, "Function"},
+	{protocol.MethodType, "Function"},
+	{protocol.MacroType, "Macro"},
+	{protocol.KeywordType, "Keyword"},
+	{protocol.ModifierType, "StorageClass"},
+	{protocol.CommentType, "Comment"},
+	{protocol.StringType, "String"},
+	{protocol.NumberType, "Number"},
+	{protocol.RegexpType, "String"},
+	{protocol.OperatorType, "Operator"},
+	{protocol.DecoratorType, "PreProc"},
+	{protocol.LabelType, "Label"},
+}
+
+// semanticTokenModifiers are the semantic token modifiers for which govim
+// defines a highlight group, along with the default attributes of the group.
+// Modifier highlights are combined with the highlight of the token type.
+var semanticTokenModifiers = []struct {
+	name  protocol.SemanticTokenModifiers
+	attrs string
+}{
+	{protocol.ModDeclaration, ""},
+	{protocol.ModDefinition, ""},
+	{protocol.ModReadonly, ""},
+	{protocol.ModStatic, ""},
+	{protocol.ModDeprecated, "term=strikethrough cterm=strikethrough gui=strikethrough"},
+	{protocol.ModAbstract, ""},
+	{protocol.ModAsync, ""},
+	{protocol.ModModification, ""},
+	{protocol.ModDocumentation, ""},
+	{protocol.ModDefaultLibrary, ""},
+}
+
+// semanticTokenHighlights is the set of highlight groups, and hence text
+// property types, defined for semantic token types and modifiers.
+var semanticTokenHighlights = func() map[config.Highlight]bool {
+	res := make(map[config.Highlight]bool)
+	for _, t := range semanticTokenTypes {
+		res[semanticTypeHighlight(string(t.name))] = true
+	}
+	for _, m := range semanticTokenModifiers {
+		res[semanticModifierHighlight(string(m.name))] = true
+	}
+	return res
+}()
+
+func semanticTypeHighlight(t string) config.Highlight {
+	return config.HighlightSemanticPrefix + config.Highlight(upperFirst(t))
+}
+
+func semanticModifierHighlight(m string) config.Highlight {
+	return config.HighlightSemanticPrefix + "Mod" + config.Highlight(upperFirst(m))
+}
+
+func upperFirst(s string) string {
+	r, n := utf8.DecodeRuneInString(s)
+	return string(unicode.ToUpper(r)) + s[n:]
+}
+
+// semanticTokensHighlightCmds returns the commands that define the default
+// highlight groups for semantic tokens.
+func semanticTokensHighlightCmds() []string {
+	var res []string
+	for _, t := range semanticTokenTypes {
+		hi := semanticTypeHighlight(string(t.name))
+		if t.link == "" {
+			res = append(res, fmt.Sprintf("highlight default %s NONE", hi))
+		} else {
+			res = append(res, fmt.Sprintf("highlight default link %s %s", hi, t.link))
+		}
+	}
+	for _, m := range semanticTokenModifiers {
+		attrs := m.attrs
+		if attrs == "" {
+			attrs = "NONE"
+		}
+		res = append(res, fmt.Sprintf("highlight default %s %s", semanticModifierHighlight(string(m.name)), attrs))
+	}
+	return res
+}
+
+// semanticTokensClientCapabilities returns the semantic token capabilities
+// govim announces to gopls.
+func semanticTokensClientCapabilities() protocol.SemanticTokensClientCapabilities {
+	res := protocol.SemanticTokensClientCapabilities{
+		DynamicRegistration: true,
+		Requests: protocol.ClientSemanticTokensRequestOptions{
+			Full: &protocol.Or_ClientSemanticTokensRequestOptions_full{
+				Value: protocol.ClientSemanticTokensRequestFullDelta{Delta: true},
+			},
+		},
+		Formats: []protocol.TokenFormat{protocol.Relative},
+	}
+	for _, t := range semanticTokenTypes {
+		res.TokenTypes = append(res.TokenTypes, string(t.name))
+	}
+	for _, m := range semanticTokenModifiers {
+		res.TokenModifiers = append(res.TokenModifiers, string(m.name))
+	}
+	return res
+}
+
+// semanticToken is a decoded semantic token within a single line. start and
+// length are in UTF-16 code units, per the LSP spec.
+type semanticToken struct {
+	start     uint32
+	length    uint32
+	tokenType uint32
+	modifiers uint32
+}
+
+// semanticTokensState is the semantic highlighting state of a buffer
+type semanticTokensState struct {
+	// resultID and data are the most recent result from gopls; they are the
+	// base for subsequent delta requests
+	resultID string
+	data     []uint32
+
+	// rendered maps 0-indexed lines to the tokens that are currently
+	// highlighted on that line
+	rendered map[uint32][]semanticToken
+
+	// dirty is the set of 0-indexed lines that have changed since tokens were
+	// last rendered, and hence need to be rendered again regardless of
+	// whether their tokens have changed
+	dirty map[uint32]bool
+
+	// cancel cancels the ongoing request for the buffer, if any
+	cancel context.CancelFunc
+}
+
+// setSemanticTokensProvider records the legend and delta support from the
+// semantic tokens options advertised by gopls, either as part of the server
+// capabilities or via a dynamic registration. opts is nil when gopls does
+// not provide semantic tokens.
+func (v *vimstate) setSemanticTokensProvider(opts interface{}) error {
+	if opts == nil {
+		return nil
+	}
+	byts, err := json.Marshal(opts)
+	if err != nil {
+		return fmt.Errorf("failed to marshal semantic tokens options: %v", err)
+	}
+	var provider struct {
+		Legend protocol.SemanticTokensLegend
+		Full   json.RawMessage
+	}
+	if err := json.Unmarshal(byts, &provider); err != nil {
+		return fmt.Errorf("failed to unmarshal semantic tokens options: %v", err)
+	}
+	var full protocol.SemanticTokensFullDelta
+	// Full is either a boolean or an object with a delta field
+	json.Unmarshal(provider.Full, &full)
+	v.semanticTokensLegend = &provider.Legend
+	v.semanticTokensDelta = full.Delta
+	return nil
+}
+
+func (v *vimstate) semanticTokensEnabled() bool {
+	return v.config.HighlightSemanticTokens != nil && *v.config.HighlightSemanticTokens && v.semanticTokensLegend != nil
+}
+
+// updateAllSemanticTokens requests semantic tokens for all loaded buffers
+func (v *vimstate) updateAllSemanticTokens() error {
+	for _, b := range v.buffers {
+		if err := v.updateSemanticTokens(b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// updateSemanticTokens requests the semantic tokens for b in the background,
+// as a delta against the previous result if gopls supports it. Any ongoing
+// request for b is cancelled so that only the latest response is rendered.
+func (v *vimstate) updateSemanticTokens(b *types.Buffer) error {
+	if !v.semanticTokensEnabled() || !b.Loaded || !strings.HasSuffix(b.Name, ".go") {
+		return nil
+	}
+	st, ok := v.semanticTokens[b.Num]
+	if !ok {
+		st = &semanticTokensState{
+			rendered: make(map[uint32][]semanticToken),
+			dirty:    make(map[uint32]bool),
+		}
+		v.semanticTokens[b.Num] = st
+	}
+	if st.cancel != nil {
+		st.cancel()
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	st.cancel = cancel
+
+	version := b.Version
+	prevID, prevData := st.resultID, st.data
+	delta := v.semanticTokensDelta && prevID != ""
+	v.tomb.Go(func() error {
+		resultID, data, err := v.fetchSemanticTokens(ctx, b, delta, prevID, prevData)
+		select {
+		case <-ctx.Done():
+			return nil
+		default:
+		}
+		if err != nil {
+			v.Logf("failed to get semantic tokens for %v: %v", b.Name, err)
+			return nil
+		}
+		v.govimplugin.Schedule(func(govim.Govim) error {
+			// A newer request has or will soon be sent, so this response is no
+			// longer relevant
+			select {
+			case <-ctx.Done():
+				return nil
+			default:
+			}
+			if v.semanticTokens[b.Num] != st || v.buffers[b.Num] != b || b.Version != version {
+				return nil
+			}
+			st.cancel = nil
+			st.resultID, st.data = resultID, data
+			v.renderSemanticTokens(b, st)
+			return nil
+		})
+		return nil
+	})
+	return nil
+}
+
+// fetchSemanticTokens returns the result ID and token data for b. If delta is
+// true, a delta against prevData (which has the result ID prevID) is requested
+// first, falling back to requesting all tokens if that fails.
+func (v *vimstate) fetchSemanticTokens(ctx context.Context, b *types.Buffer, delta bool, prevID string, prevData []uint32) (string, []uint32, error) {
+	if delta {
+		res, err := v.server.SemanticTokensFullDelta(ctx, &protocol.SemanticTokensDeltaParams{
+			TextDocument:     b.ToTextDocumentIdentifier(),
+			PreviousResultID: prevID,
+		})
+		if err == nil {
+			var resultID string
+			var data []uint32
+			resultID, data, err = applySemanticTokensDelta(res, prevData)
+			if err == nil {
+				return resultID, data, nil
+			}
+		}
+		if ctx.Err() != nil {
+			return "", nil, ctx.Err()
+		}
+		v.Logf("semanticTokens/full/delta failed for %v, requesting all tokens: %v", b.Name, err)
+	}
+	res, err := v.server.SemanticTokensFull(ctx, &protocol.SemanticTokensParams{
+		TextDocument: b.ToTextDocumentIdentifier(),
+	})
+	if err != nil {
+		return "", nil, fmt.Errorf("call to gopls.SemanticTokensFull failed: %v", err)
+	}
+	if res == nil {
+		return "", nil, nil
+	}
+	return res.ResultID, res.Data, nil
+}
+
+// applySemanticTokensDelta applies a semanticTokens/full/delta response, which
+// is either a complete SemanticTokens or a SemanticTokensDelta, to prevData.
+func applySemanticTokensDelta(res interface{}, prevData []uint32) (string, []uint32, error) {
+	byts, err := json.Marshal(res)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to marshal delta response: %v", err)
+	}
+	var resp struct {
+		ResultID string                        `json:"resultId"`
+		Data     *[]uint32                     `json:"data"`
+		Edits    []protocol.SemanticTokensEdit `json:"edits"`
+	}
+	if err := json.Unmarshal(byts, &resp); err != nil {
+		return "", nil, fmt.Errorf("failed to unmarshal delta response: %v", err)
+	}
+	if resp.Data != nil {
+		return resp.ResultID, *resp.Data, nil
+	}
+
+	// Edits refer to positions in the previous data, so apply them in order
+	// of position whilst copying the unchanged parts in between.
+	edits := resp.Edits
+	sort.SliceStable(edits, func(i, j int) bool {
+		return edits[i].Start < edits[j].Start
+	})
+	var data []uint32
+	var pos uint32
+	for _, e := range edits {
+		if e.Start < pos || e.Start+e.DeleteCount > uint32(len(prevData)) {
+			return "", nil, fmt.Errorf("invalid semantic tokens edit %v for data of length %d", e, len(prevData))
+		}
+		data = append(data, prevData[pos:e.Start]...)
+		data = append(data, e.Data...)
+		pos = e.Start + e.DeleteCount
+	}
+	data = append(data, prevData[pos:]...)
+	return resp.ResultID, data, nil
+}
+
+// decodeSemanticTokens decodes data, in the relative format defined by LSP,
+// into tokens by 0-indexed line.
+func decodeSemanticTokens(data []uint32) map[uint32][]semanticToken {
+	res := make(map[uint32][]semanticToken)
+	var line, start uint32
+	for i := 0; i+4 < len(data); i += 5 {
+		if data[i] > 0 {
+			line += data[i]
+			start = 0
+		}
+		start += data[i+1]
+		res[line] = append(res[line], semanticToken{
+			start:     start,
+			length:    data[i+2],
+			tokenType: data[i+3],
+			modifiers: data[i+4],
+		})
+	}
+	return res
+}
+
+// renderSemanticTokens updates the text properties of b to reflect st.data.
+// Only lines whose tokens have changed, or that are dirty, are updated.
+func (v *vimstate) renderSemanticTokens(b *types.Buffer, st *semanticTokensState) {
+	legend := v.semanticTokensLegend
+	tokens := decodeSemanticTokens(st.data)
+
+	lineSet := make(map[uint32]bool)
+	for l := range st.rendered {
+		lineSet[l] = true
+	}
+	for l := range tokens {
+		lineSet[l] = true
+	}
+	for l := range st.dirty {
+		lineSet[l] = true
+	}
+	var lines []uint32
+	for l := range lineSet {
+		if !st.dirty[l] && equalSemanticTokens(st.rendered[l], tokens[l]) {
+			continue
+		}
+		lines = append(lines, l)
+	}
+	sort.Slice(lines, func(i, j int) bool {
+		return lines[i] < lines[j]
+	})
+
+	v.BatchStart()
+	defer v.BatchCancelIfNotEnded()
+	for _, l := range lines {
+		v.BatchChannelCall("prop_remove", struct {
+			ID    int `json:"id"`
+			BufNr int `json:"bufnr"`
+			All   int `json:"all"`
+		}{types.SemanticTokensTextPropID, b.Num, 1}, l+1, l+1)
+		for _, t := range tokens[l] {
+			start, err := types.PointFromPosition(b, protocol.Position{Line: l, Character: t.start})
+			if err != nil {
+				v.Logf("failed to convert semantic token start to point: %v", err)
+				continue
+			}
+			end, err := types.PointFromPosition(b, protocol.Position{Line: l, Character: t.start + t.length})
+			if err != nil {
+				v.Logf("failed to convert semantic token end to point: %v", err)
+				continue
+			}
+			var his []config.Highlight
+			if int(t.tokenType) < len(legend.TokenTypes) {
+				his = append(his, semanticTypeHighlight(legend.TokenTypes[t.tokenType]))
+			}
+			for i, m := range legend.TokenModifiers {
+				if t.modifiers&(1<<uint(i)) != 0 {
+					his = append(his, semanticModifierHighlight(m))
+				}
+			}
+			for _, hi := range his {
+				if !semanticTokenHighlights[hi] {
+					continue
+				}
+				v.BatchAssertChannelCall(assertPropAdd, "prop_add",
+					start.Line(),
+					start.Col(),
+					propAddDict{string(hi), types.SemanticTokensTextPropID, end.Line(), end.Col(), b.Num},
+				)
+			}
+		}
+	}
+	v.MustBatchEnd()
+	st.rendered = tokens
+	st.dirty = make(map[uint32]bool)
+}
+
+func equalSemanticTokens(a, b []semanticToken) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// shiftSemanticTokens adjusts the rendered state of b for changes made to the
+// buffer, in the same way that Vim moves the text properties themselves. The
+// changed lines are marked dirty.
+func (v *vimstate) shiftSemanticTokens(b *types.Buffer, changes []bufChangedChange) {
+	st, ok := v.semanticTokens[b.Num]
+	if !ok {
+		return
+	}
+	for _, c := range changes {
+		first, end := c.Lnum-1, c.End-1
+		shift := func(l uint32) (uint32, bool) {
+			switch {
+			case l < first:
+				return l, true
+			case l >= end:
+				return uint32(int(l) + c.Added), true
+			}
+			return 0, false
+		}
+		rendered := make(map[uint32][]semanticToken, len(st.rendered))
+		for l, toks := range st.rendered {
+			if nl, ok := shift(l); ok {
+				rendered[nl] = toks
+			}
+		}
+		dirty := make(map[uint32]bool, len(st.dirty))
+		for l := range st.dirty {
+			if nl, ok := shift(l); ok {
+				dirty[nl] = true
+			}
+		}
+		for l := int(first); l < int(end)+c.Added; l++ {
+			dirty[uint32(l)] = true
+		}
+		st.rendered, st.dirty = rendered, dirty
+	}
+}
+
+// resetSemanticTokens cancels any ongoing request for the buffer bufnr and
+// forgets its state, e.g. because Vim removed all text properties when the
+// buffer was unloaded.
+func (v *vimstate) resetSemanticTokens(bufnr int) {
+	if st, ok := v.semanticTokens[bufnr]; ok && st.cancel != nil {
+		st.cancel()
+	}
+	delete(v.semanticTokens, bufnr)
+}
+
+// removeSemanticTokens removes the semantic token highlights from all buffers
+func (v *vimstate) removeSemanticTokens() {
+	for bufnr := range v.semanticTokens {
+		v.resetSemanticTokens(bufnr)
+	}
+	v.removeTextProps(types.SemanticTokensTextPropID)
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/govim/govim"
+	"github.com/govim/govim/cmd/govim/internal/golang_org_x_tools_gopls/protocol"
+	"github.com/govim/govim/cmd/govim/internal/types"
+)
+
+// showDocument opens the file that gopls asked to be shown. If TakeFocus is
+// not set, the file is opened without replacing the buffer of the current
+// window, which remains the current window. If there is a selection, the
+// cursor is moved to its start and the selection can be reselected with gv.
+func (v *vimstate) showDocument(params *protocol.ShowDocumentParams) error {
+	loc := protocol.Location{URI: protocol.DocumentURI(params.URI)}
+	if params.Selection != nil {
+		loc.Range = *params.Selection
+	}
+	origin := v.ParseInt(v.ChannelCall("win_getid"))
+	var args []string
+	if !params.TakeFocus {
+		args = []string{"useopen,split"}
+	}
+	if err := v.loadLocation(govim.CommModList{}, loc, args...); err != nil {
+		return err
+	}
+	if params.Selection != nil && params.Selection.Start != params.Selection.End {
+		if err := v.markSelection(*params.Selection); err != nil {
+			return err
+		}
+	}
+	if !params.TakeFocus {
+		v.ChannelCall("win_gotoid", origin)
+	}
+	return nil
+}
+
+// markSelection sets the marks of the last visual selection in the current
+// buffer to r, where the end of r is exclusive.
+func (v *vimstate) markSelection(r protocol.Range) error {
+	b, ok := v.buffers[v.ParseInt(v.ChannelExpr(`bufnr("")`))]
+	if !ok {
+		return nil
+	}
+	start, err := types.PointFromPosition(b, r.Start)
+	if err != nil {
+		return fmt.Errorf("failed to derive point from position: %v", err)
+	}
+	end, err := types.PointFromPosition(b, r.End)
+	if err != nil {
+		return fmt.Errorf("failed to derive point from position: %v", err)
+	}
+	endLine, endCol := end.Line(), end.Col()-1
+	if endCol < 1 && endLine > start.Line() {
+		// The selection ends with the preceding line
+		endLine--
+		endCol = v.ParseInt(v.ChannelCall("col", []interface{}{endLine, "$"})) - 1
+	}
+	v.BatchStart()
+	v.BatchChannelCall("setpos", "'<", []int{0, start.Line(), start.Col(), 0})
+	v.BatchChannelCall("setpos", "'>", []int{0, endLine, endCol, 0})
+	v.MustBatchEnd()
+	return nil
+}
+
+// copyURL copies a URL that gopls asked to be shown to registers, for when
+// there is no command to open it with.
+func (v *vimstate) copyURL(url string) {
+	v.BatchStart()
+	v.BatchChannelCall("setreg", `"`, url)
+	v.BatchChannelExprf(`has("clipboard")`)
+	res := v.MustBatchEnd()
+	if v.ParseInt(res[1]) == 1 {
+		v.ChannelCall("setreg", "+", url)
+	}
+	v.ChannelExf("echom %q", "gopls: copied "+url)
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/govim/govim"
 	"github.com/govim/govim/cmd/govim/config"
 	"github.com/govim/govim/cmd/govim/internal/golang_org_x_tools_gopls/protocol"
+	"github.com/govim/govim/cmd/govim/internal/types"
 )
 
 func (v *vimstate) suggestFixes(flags govim.CommandFlags, args ...string) error {
@@ -66,18 +67,8 @@ func (v *vimstate) suggestFixes(flags govim.CommandFlags, args ...string) error
 	end := pos.ToPosition()
 	textDoc := cb.ToTextDocumentIdentifier()
 
-	var coveredDiags []protocol.Diagnostic
-	v.diagnosticsChangedLock.Lock()
-	if diags, ok := v.rawDiagnostics[cb.URI()]; ok {
-		for _, d := range diags.Diagnostics {
-			// TODO: should we go for "current line" as default?
-			if int(pos.ToPosition().Line) >= int(d.Range.Start.Line) &&
-				int(pos.ToPosition().Line) <= int(d.Range.End.Line) {
-				coveredDiags = append(coveredDiags, d)
-			}
-		}
-	}
-	v.diagnosticsChangedLock.Unlock()
+	// TODO: should we go for "current line" as default?
+	coveredDiags := v.diagnosticsCoveringLines(cb, start.Line, end.Line)
 
 	params := &protocol.CodeActionParams{
 		TextDocument: textDoc,
@@ -136,6 +127,61 @@ type suggestedFix struct {
 	msg     string
 	command *protocol.Command
 	edit    protocol.WorkspaceEdit
+
+	// action is the code action the fix was created from, if it has yet to
+	// be resolved via gopls.ResolveCodeAction
+	action *protocol.CodeAction
+
+	// lens indicates that command is that of a code lens, which is run via
+	// runCodeLens so that its progress is reported
+	lens bool
+}
+
+func newSuggestedFix(ca protocol.CodeAction) suggestedFix {
+	fix := suggestedFix{
+		msg:     ca.Title,
+		command: ca.Command,
+	}
+	if ca.Edit != nil {
+		fix.edit = *ca.Edit
+	}
+	if codeActionNeedsResolve(ca) {
+		fix.action = &ca
+	}
+	return fix
+}
+
+// resolve resolves the edit of fix if it was not computed up front
+func (fix *suggestedFix) resolve(v *vimstate) error {
+	if fix.action == nil {
+		return nil
+	}
+	ca, err := v.resolveCodeAction(*fix.action)
+	if err != nil {
+		return err
+	}
+	fix.command = ca.Command
+	if ca.Edit != nil {
+		fix.edit = *ca.Edit
+	}
+	fix.action = nil
+	return nil
+}
+
+// diagnosticsCoveringLines returns the diagnostics of b that cover any of the
+// 0-indexed lines from start to end inclusive.
+func (v *vimstate) diagnosticsCoveringLines(b *types.Buffer, start, end uint32) []protocol.Diagnostic {
+	var res []protocol.Diagnostic
+	v.diagnosticsChangedLock.Lock()
+	defer v.diagnosticsChangedLock.Unlock()
+	if diags, ok := v.rawDiagnostics[b.URI()]; ok {
+		for _, d := range diags.Diagnostics {
+			if end >= d.Range.Start.Line && start <= d.Range.End.Line {
+				res = append(res, d)
+			}
+		}
+	}
+	return res
 }
 
 func diagSuggestions(codeActions []protocol.CodeAction) []resolvableDiag {
@@ -161,7 +207,7 @@ func diagSuggestions(codeActions []protocol.CodeAction) []resolvableDiag {
 			if _, exist := resolvableDiags[k]; !exist {
 				resolvableDiags[k] = make([]suggestedFix, 0)
 			}
-			resolvableDiags[k] = append(resolvableDiags[k], suggestedFix{ca.Title, ca.Command, *ca.Edit})
+			resolvableDiags[k] = append(resolvableDiags[k], newSuggestedFix(ca))
 		}
 	}
 
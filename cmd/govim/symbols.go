@@ -0,0 +1,187 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/govim/govim"
+	"github.com/govim/govim/cmd/govim/config"
+	"github.com/govim/govim/cmd/govim/internal/golang_org_x_tools_gopls/protocol"
+)
+
+// symbolsPicker is the state of the popup opened by CommandSymbols. The query
+// is edited by the popup filter in Vim, which calls FunctionSymbolsQuery each
+// time the query changes.
+type symbolsPicker struct {
+	popupID int
+	query   string
+	results []protocol.SymbolInformation
+
+	// mods are the command modifiers CommandSymbols was called with, used
+	// when loading the selected symbol
+	mods govim.CommModList
+
+	// cancel cancels the ongoing gopls.Symbol request, if any
+	cancel context.CancelFunc
+}
+
+func (v *vimstate) symbols(flags govim.CommandFlags, args ...string) error {
+	v.closeSymbolsPicker()
+
+	opts := map[string]interface{}{
+		"pos":        "center",
+		"minwidth":   60,
+		"maxwidth":   120,
+		"minheight":  1,
+		"maxheight":  15,
+		"border":     []int{},
+		"padding":    []int{0, 1, 0, 1},
+		"cursorline": 1,
+		"wrap":       0,
+		"mapping":    0,
+		"filter":     "GOVIM_internal_SymbolsFilter",
+		"callback":   "GOVIM" + config.FunctionSymbolsSelection,
+		"title":      symbolsTitle(""),
+	}
+	p := &symbolsPicker{
+		mods: flags.Mods,
+	}
+	p.popupID = v.ParseInt(v.ChannelCall("popup_create", []string{""}, opts))
+	v.symbolsPicker = p
+
+	query := strings.Join(args, " ")
+	if query == "" {
+		return nil
+	}
+	// The popup filter reads the query from the popup window, so that typing
+	// continues from the initial query
+	v.ChannelCall("setwinvar", p.popupID, "govim_symbols_query", query)
+	return v.updateSymbolsPicker(p, query)
+}
+
+// symbolsQuery is called by the popup filter of the symbols picker each time
+// the query is edited. The results are updated in the background.
+func (v *vimstate) symbolsQuery(args ...json.RawMessage) (interface{}, error) {
+	var popupID int
+	var query string
+	v.Parse(args[0], &popupID)
+	v.Parse(args[1], &query)
+	p := v.symbolsPicker
+	if p == nil || p.popupID != popupID {
+		return nil, fmt.Errorf("no symbols picker with popup id %d", popupID)
+	}
+	return nil, v.updateSymbolsPicker(p, query)
+}
+
+func (v *vimstate) updateSymbolsPicker(p *symbolsPicker, query string) error {
+	if p.cancel != nil {
+		p.cancel()
+		p.cancel = nil
+	}
+	p.query = query
+	v.ChannelCall("popup_setoptions", p.popupID, map[string]interface{}{
+		"title": symbolsTitle(query),
+	})
+	if query == "" {
+		p.results = nil
+		v.ChannelCall("popup_settext", p.popupID, []string{""})
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	p.cancel = cancel
+	v.tomb.Go(func() error {
+		// gopls applies the SymbolMatcher and SymbolStyle config to the query
+		res, err := v.server.Symbol(ctx, &protocol.WorkspaceSymbolParams{
+			Query: query,
+		})
+		select {
+		case <-ctx.Done():
+			return nil
+		default:
+		}
+		v.govimplugin.Schedule(func(govim.Govim) error {
+			// The query has since changed, or the picker has been closed
+			select {
+			case <-ctx.Done():
+				return nil
+			default:
+			}
+			p.cancel = nil
+			if err != nil {
+				return fmt.Errorf("failed to call gopls.Symbol: %v", err)
+			}
+			p.results = res
+			v.ChannelCall("popup_settext", p.popupID, symbolsLines(res))
+			v.ChannelCall("win_execute", p.popupID, "call cursor(1, 1)")
+			return nil
+		})
+		return nil
+	})
+	return nil
+}
+
+// symbolsSelection is the callback of the symbols picker popup. selected is
+// the 1-indexed line of the selected symbol, or less than 1 if the popup was
+// closed without a selection.
+func (v *vimstate) symbolsSelection(args ...json.RawMessage) (interface{}, error) {
+	var popupID int
+	var selected int
+	v.Parse(args[0], &popupID)
+	v.Parse(args[1], &selected)
+	p := v.symbolsPicker
+	if p == nil || p.popupID != popupID {
+		return nil, nil
+	}
+	if p.cancel != nil {
+		p.cancel()
+	}
+	v.symbolsPicker = nil
+	if selected < 1 || selected > len(p.results) {
+		return nil, nil
+	}
+	sym := p.results[selected-1]
+	return nil, v.jumpToLocation(p.mods, sym.Name, sym.Location)
+}
+
+// closeSymbolsPicker closes the symbols picker popup, if open
+func (v *vimstate) closeSymbolsPicker() {
+	p := v.symbolsPicker
+	if p == nil {
+		return
+	}
+	if p.cancel != nil {
+		p.cancel()
+	}
+	v.symbolsPicker = nil
+	v.ChannelCall("popup_close", p.popupID)
+}
+
+func symbolsTitle(query string) string {
+	return fmt.Sprintf(" Symbols: %s ", query)
+}
+
+// symbolsLines formats symbols as aligned columns of name, kind and container
+func symbolsLines(syms []protocol.SymbolInformation) []string {
+	if len(syms) == 0 {
+		return []string{"No symbols found"}
+	}
+	var nameWidth, kindWidth int
+	kinds := make([]string, len(syms))
+	for i, s := range syms {
+		kinds[i] = fmt.Sprint(s.Kind)
+		if n := len(s.Name); n > nameWidth {
+			nameWidth = n
+		}
+		if n := len(kinds[i]); n > kindWidth {
+			kindWidth = n
+		}
+	}
+	lines := make([]string, len(syms))
+	for i, s := range syms {
+		lines[i] = strings.TrimRight(fmt.Sprintf("%-*s  %-*s  %s", nameWidth, s.Name, kindWidth, kinds[i], s.ContainerName), " ")
+	}
+	return lines
+}
@@ -16,7 +16,8 @@ import (
 // only definitions we need for the purposes of testing
 
 const (
-	CommandHello config.Command = "Hello"
+	CommandHello              config.Command = "Hello"
+	CommandShowMessageRequest config.Command = "ShowMessageRequest"
 )
 
 const (
@@ -29,6 +30,8 @@ const (
 	FunctionNonBatchCallInBatch config.Function = "NonBatchCallInBatch"
 	FunctionIgnoreErrorInBatch  config.Function = "IgnoreErrorInBatch"
 	FunctionShowMessagePopup    config.Function = config.InternalFunctionPrefix + "ShowMessagePopup"
+	FunctionShowMessageRequest  config.Function = config.InternalFunctionPrefix + "ShowMessageRequest"
+	FunctionShowDocument        config.Function = config.InternalFunctionPrefix + "ShowDocument"
 )
 
 func (g *govimplugin) InitTestAPI() {
@@ -40,6 +43,9 @@ func (g *govimplugin) InitTestAPI() {
 	g.DefineCommand(string(CommandHello), g.vimstate.helloComm, govim.NArgsZeroOrOne)
 	g.DefineFunction(string(FunctionDumpPopups), []string{}, g.vimstate.dumpPopups)
 	g.DefineFunction(string(FunctionShowMessagePopup), []string{}, g.vimstate.showMessagePopup)
+	g.DefineFunction(string(FunctionShowMessageRequest), []string{"message", "actions"}, g.vimstate.showMessageRequestPopup)
+	g.DefineCommand(string(CommandShowMessageRequest), g.vimstate.showMessageRequestBlocking, govim.NArgsOneOrMore)
+	g.DefineFunction(string(FunctionShowDocument), []string{"params"}, g.vimstate.showDocumentRequest)
 	g.DefineFunction(string(FunctionSimpleBatch), []string{}, g.vimstate.simpleBatch)
 	g.DefineFunction(string(FunctionCancelBatch), []string{}, g.vimstate.cancelBatch)
 	g.DefineFunction(string(FunctionBadBatch), []string{}, g.vimstate.badBatch)
@@ -90,6 +96,50 @@ func (v *vimstate) showMessagePopup(args ...json.RawMessage) (interface{}, error
 	return "", nil
 }
 
+// showMessageRequestPopup sends a prompt to govim as if it were sent by gopls.
+// The response is logged, such that scenarios can assert the action chosen.
+func (v *vimstate) showMessageRequestPopup(args ...json.RawMessage) (interface{}, error) {
+	params := &protocol.ShowMessageRequestParams{Type: protocol.Info}
+	var actions []string
+	v.Parse(args[0], &params.Message)
+	v.Parse(args[1], &actions)
+	for _, a := range actions {
+		params.Actions = append(params.Actions, protocol.MessageActionItem{Title: a})
+	}
+	v.tomb.Go(func() error {
+		_, err := v.ShowMessageRequest(context.Background(), params)
+		return err
+	})
+	return "", nil
+}
+
+// showMessageRequestBlocking sends a prompt to govim as if it were sent by
+// gopls whilst executing a command, i.e. during a blocking call on the Vim
+// thread. The arguments are the actions of the prompt. The response is logged,
+// such that scenarios can assert the action chosen.
+func (v *vimstate) showMessageRequestBlocking(flags govim.CommandFlags, args ...string) error {
+	params := &protocol.ShowMessageRequestParams{Type: protocol.Info, Message: "Run go mod tidy?"}
+	for _, a := range args {
+		params.Actions = append(params.Actions, protocol.MessageActionItem{Title: a})
+	}
+	return v.callBlocking(func() error {
+		_, err := v.ShowMessageRequest(context.Background(), params)
+		return err
+	})
+}
+
+// showDocumentRequest asks govim to show a document as if gopls asked, where
+// the argument is a dict of protocol.ShowDocumentParams.
+func (v *vimstate) showDocumentRequest(args ...json.RawMessage) (interface{}, error) {
+	var params protocol.ShowDocumentParams
+	v.Parse(args[0], &params)
+	v.tomb.Go(func() error {
+		_, err := v.ShowDocument(context.Background(), &params)
+		return err
+	})
+	return "", nil
+}
+
 func (v *vimstate) simpleBatch(args ...json.RawMessage) (interface{}, error) {
 	v.BatchStart()
 	defer v.BatchCancelIfNotEnded()
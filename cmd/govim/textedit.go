@@ -164,8 +164,29 @@ func (v *vimstate) applyProtocolTextEdits(b *types.Buffer, edits []protocol.Text
 			},
 		},
 	}
+	if err := v.server.DidChange(context.Background(), params); err != nil {
+		return err
+	}
 
-	return v.server.DidChange(context.Background(), params)
+	bufChanges := make([]bufChangedChange, len(changes))
+	for i, e := range changes {
+		bufChanges[i] = e.bufChange()
+	}
+	return v.handleBufferChanged(b, bufChanges)
+}
+
+// bufChange returns e as the change a Vim listener would report for it
+func (e textEdit) bufChange() bufChangedChange {
+	switch e.call {
+	case "setbufline":
+		return bufChangedChange{Lnum: uint32(e.start), End: uint32(e.start) + 1}
+	case "deletebufline":
+		return bufChangedChange{Lnum: uint32(e.start), End: uint32(e.end) + 1, Added: e.start - e.end - 1}
+	case "appendbufline":
+		return bufChangedChange{Lnum: uint32(e.start) + 1, End: uint32(e.start) + 1, Added: len(e.lines)}
+	default:
+		panic(fmt.Errorf("unknown change type: %v", e.call))
+	}
 }
 
 // sortEdits orders edits by (start, end) offset.
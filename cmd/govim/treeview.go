@@ -0,0 +1,270 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/govim/govim"
+	"github.com/govim/govim/cmd/govim/config"
+	"github.com/govim/govim/cmd/govim/internal/golang_org_x_tools_gopls/protocol"
+)
+
+// treeExpandAllDepth limits how deep a recursive expansion of a tree node
+// goes. Call graphs in particular can be cyclic, so without a limit a
+// recursive expansion might never terminate.
+const treeExpandAllDepth = 5
+
+// treeActions are the buffer-local normal mode mappings defined in a tree
+// view buffer, mapped to the action passed to FunctionTreeAction.
+var treeActions = []struct {
+	key    string
+	action string
+}{
+	{"<CR>", "select"},
+	{"o", "toggle"},
+	{"za", "toggle"},
+	{"O", "expandall"},
+	{"Q", "quickfix"},
+	{"q", "close"},
+}
+
+// treeNode is a single node in a treeView. A node with a non-nil expand
+// function lazily fetches its children the first time it is expanded.
+type treeNode struct {
+	// text is the text shown for the node, excluding indentation and the
+	// expand/collapse marker
+	text string
+
+	// locs are the locations represented by the node. The first location is
+	// the target when selecting the node; all of them are used when the tree
+	// is flattened into the quickfix list.
+	locs []protocol.Location
+
+	children []*treeNode
+	expanded bool
+	fetched  bool
+
+	// expand is called to fetch the children of a node. A nil expand means the
+	// node is a leaf.
+	expand func() ([]*treeNode, error)
+}
+
+// treeView is a scratch buffer that renders a tree of nodes, one node per
+// line, below a title line.
+type treeView struct {
+	bufNr int
+
+	// originWinID is the window from which the tree view was opened. Selecting
+	// a node loads its location in that window.
+	originWinID int
+
+	title string
+	roots []*treeNode
+
+	// lines maps the 0-indexed lines below the title to the node rendered on
+	// that line
+	lines []*treeNode
+}
+
+func (t *treeView) nodeAt(line int) *treeNode {
+	i := line - 2
+	if i < 0 || i >= len(t.lines) {
+		return nil
+	}
+	return t.lines[i]
+}
+
+// flatten returns the locations of all nodes currently visible in the tree
+func (t *treeView) flatten() []protocol.Location {
+	var locs []protocol.Location
+	var walk func(nodes []*treeNode)
+	walk = func(nodes []*treeNode) {
+		for _, n := range nodes {
+			locs = append(locs, n.locs...)
+			if n.expanded {
+				walk(n.children)
+			}
+		}
+	}
+	walk(t.roots)
+	return locs
+}
+
+func (v *vimstate) expandTreeNode(n *treeNode) error {
+	if !n.fetched && n.expand != nil {
+		children, err := n.expand()
+		if err != nil {
+			return err
+		}
+		n.children = children
+		n.fetched = true
+	}
+	n.expanded = true
+	return nil
+}
+
+func (v *vimstate) expandTreeNodeAll(n *treeNode, depth int) error {
+	if depth == 0 {
+		return nil
+	}
+	if err := v.expandTreeNode(n); err != nil {
+		return err
+	}
+	for _, c := range n.children {
+		if err := v.expandTreeNodeAll(c, depth-1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// openTreeView creates a new scratch buffer for a tree of nodes and opens it
+// in a split according to mods, falling back to split when no mods are
+// given. Each root is expanded before the tree is rendered.
+func (v *vimstate) openTreeView(mods govim.CommModList, split, kind, title string, roots []*treeNode) (*treeView, error) {
+	for _, r := range roots {
+		if err := v.expandTreeNode(r); err != nil {
+			return nil, err
+		}
+	}
+
+	// Remove tree views whose buffers have since been wiped out
+	for bufnr := range v.treeViews {
+		if v.ParseInt(v.ChannelCall("bufexists", bufnr)) == 0 {
+			delete(v.treeViews, bufnr)
+		}
+	}
+
+	tv := &treeView{
+		originWinID: v.ParseInt(v.ChannelCall("win_getid")),
+		title:       title,
+		roots:       roots,
+	}
+	v.lastTreeViewID++
+	bufName := fmt.Sprintf("govim-%s-%d", kind, v.lastTreeViewID)
+	tv.bufNr = v.ParseInt(v.ChannelCall("bufadd", bufName))
+	v.ChannelExf("silent call bufload(%d)", tv.bufNr)
+	v.BatchStart()
+	v.BatchChannelCall("setbufvar", tv.bufNr, "&buftype", "nofile")
+	v.BatchChannelCall("setbufvar", tv.bufNr, "&bufhidden", "wipe")
+	v.BatchChannelCall("setbufvar", tv.bufNr, "&swapfile", 0)
+	v.BatchChannelCall("setbufvar", tv.bufNr, "&buflisted", 0)
+	v.MustBatchEnd()
+	v.treeViews[tv.bufNr] = tv
+
+	if len(mods) > 0 {
+		v.ChannelExf("%v split %s", mods, bufName)
+	} else {
+		v.ChannelExf("%s %s", split, bufName)
+	}
+	v.ChannelEx("setlocal nonumber norelativenumber nowrap nofoldenable cursorline")
+	for _, a := range treeActions {
+		v.ChannelExf(`nnoremap <buffer> <silent> %s :call %s%s(%q, bufnr(""), line("."))<CR>`, a.key, v.Prefix(), config.FunctionTreeAction, a.action)
+	}
+	v.renderTreeView(tv)
+	return tv, nil
+}
+
+func (v *vimstate) renderTreeView(tv *treeView) {
+	tv.lines = tv.lines[:0]
+	text := []string{tv.title}
+	var walk func(nodes []*treeNode, depth int)
+	walk = func(nodes []*treeNode, depth int) {
+		for _, n := range nodes {
+			marker := "  "
+			switch {
+			case n.expanded && len(n.children) > 0:
+				marker = "- "
+			case !n.fetched && n.expand != nil:
+				marker = "+ "
+			case !n.expanded && len(n.children) > 0:
+				marker = "+ "
+			}
+			text = append(text, strings.Repeat("  ", depth)+marker+n.text)
+			tv.lines = append(tv.lines, n)
+			if n.expanded {
+				walk(n.children, depth+1)
+			}
+		}
+	}
+	walk(tv.roots, 0)
+
+	v.BatchStart()
+	v.BatchChannelCall("setbufvar", tv.bufNr, "&modifiable", 1)
+	v.BatchChannelCall("deletebufline", tv.bufNr, 1, "$")
+	v.BatchChannelCall("setbufline", tv.bufNr, 1, text)
+	v.BatchChannelCall("setbufvar", tv.bufNr, "&modifiable", 0)
+	v.MustBatchEnd()
+}
+
+func (v *vimstate) treeAction(args ...json.RawMessage) (interface{}, error) {
+	action := v.ParseString(args[0])
+	bufnr := v.ParseInt(args[1])
+	line := v.ParseInt(args[2])
+
+	tv, ok := v.treeViews[bufnr]
+	if !ok {
+		return nil, fmt.Errorf("couldn't find tree view for buffer %d", bufnr)
+	}
+
+	switch action {
+	case "close":
+		delete(v.treeViews, bufnr)
+		v.ChannelExf("bwipeout %d", bufnr)
+		return nil, nil
+	case "quickfix":
+		locs := tv.flatten()
+		if len(locs) == 0 {
+			return nil, nil
+		}
+		v.populateQuickfix(locs, len(tv.roots) == 1)
+		return nil, nil
+	}
+
+	n := tv.nodeAt(line)
+	if n == nil {
+		return nil, nil
+	}
+	switch action {
+	case "select":
+		if len(n.locs) == 0 {
+			return nil, nil
+		}
+		if v.ParseInt(v.ChannelCall("win_gotoid", tv.originWinID)) == 0 {
+			v.ChannelEx("wincmd p")
+		}
+		return nil, v.loadLocation(nil, n.locs[0])
+	case "toggle":
+		if n.expanded {
+			n.expanded = false
+		} else if err := v.expandTreeNode(n); err != nil {
+			return nil, err
+		}
+	case "expandall":
+		if err := v.expandTreeNodeAll(n, treeExpandAllDepth); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unknown tree action %q", action)
+	}
+	v.renderTreeView(tv)
+	v.ChannelCall("cursor", line, 1)
+	return nil, nil
+}
+
+// relLocation formats loc as a file:line string, relative to the working
+// directory where possible, for display purposes.
+func (v *vimstate) relLocation(loc protocol.Location) string {
+	return fmt.Sprintf("%s:%d", v.relPath(loc.URI.Path()), loc.Range.Start.Line+1)
+}
+
+// relPath returns fn relative to the working directory where possible, for
+// display purposes.
+func (v *vimstate) relPath(fn string) string {
+	if rel, err := filepath.Rel(v.workingDirectory, fn); err == nil && !strings.HasPrefix(rel, "..") {
+		return rel
+	}
+	return fn
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/govim/govim"
+	"github.com/govim/govim/cmd/govim/internal/golang_org_x_tools_gopls/protocol"
+)
+
+const (
+	typeHierarchySupertypes = "supertypes"
+	typeHierarchySubtypes   = "subtypes"
+)
+
+// typeHierarchy opens a tree view of the supertypes and/or subtypes of the
+// type under the cursor. An optional argument of "supertypes" or "subtypes"
+// restricts the tree to that direction.
+func (v *vimstate) typeHierarchy(flags govim.CommandFlags, args ...string) error {
+	dirs := []string{typeHierarchySupertypes, typeHierarchySubtypes}
+	if len(args) == 1 {
+		switch args[0] {
+		case typeHierarchySupertypes, typeHierarchySubtypes:
+			dirs = []string{args[0]}
+		default:
+			return fmt.Errorf("invalid argument %q; must be one of %q or %q", args[0], typeHierarchySupertypes, typeHierarchySubtypes)
+		}
+	}
+
+	b, pos, err := v.bufCursorPos()
+	if err != nil {
+		return fmt.Errorf("failed to get current position: %v", err)
+	}
+	params := &protocol.TypeHierarchyPrepareParams{
+		TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+			TextDocument: b.ToTextDocumentIdentifier(),
+			Position:     pos.ToPosition(),
+		},
+	}
+	items, err := v.server.PrepareTypeHierarchy(context.Background(), params)
+	if err != nil {
+		return fmt.Errorf("call to gopls.PrepareTypeHierarchy failed: %v", err)
+	}
+	if len(items) == 0 {
+		return fmt.Errorf("no type found under cursor")
+	}
+
+	var roots []*treeNode
+	for _, item := range items {
+		root := &treeNode{
+			text: v.typeHierarchyText(item),
+			locs: []protocol.Location{{URI: item.URI, Range: item.SelectionRange}},
+		}
+		for _, dir := range dirs {
+			// Each direction is a group node without a location of its own, so
+			// that both can be shown (and folded) side by side under the root.
+			group := &treeNode{text: dir}
+			group.expand = v.typeHierarchyExpand(item, dir)
+			root.children = append(root.children, group)
+		}
+		root.fetched = true
+		roots = append(roots, root)
+	}
+
+	title := "Type hierarchy of " + items[0].Name
+	_, err = v.openTreeView(flags.Mods, "vertical botright 50split", "typehierarchy", title, roots)
+	return err
+}
+
+func (v *vimstate) typeHierarchyComplete(args ...json.RawMessage) (interface{}, error) {
+	lead := v.ParseString(args[0])
+	var results []string
+	for _, d := range []string{typeHierarchySupertypes, typeHierarchySubtypes} {
+		if strings.HasPrefix(d, lead) {
+			results = append(results, d)
+		}
+	}
+	return results, nil
+}
+
+func (v *vimstate) typeHierarchyText(item protocol.TypeHierarchyItem) string {
+	loc := protocol.Location{URI: item.URI, Range: item.SelectionRange}
+	return fmt.Sprintf("%s  %s", item.Name, v.relLocation(loc))
+}
+
+// typeHierarchyExpand returns a function that fetches the supertypes or
+// subtypes of item, depending on dir, as tree nodes. Each of those nodes can
+// in turn be expanded in the same direction.
+func (v *vimstate) typeHierarchyExpand(item protocol.TypeHierarchyItem, dir string) func() ([]*treeNode, error) {
+	return func() ([]*treeNode, error) {
+		var items []protocol.TypeHierarchyItem
+		var err error
+		switch dir {
+		case typeHierarchySupertypes:
+			items, err = v.server.Supertypes(context.Background(), &protocol.TypeHierarchySupertypesParams{Item: item})
+			if err != nil {
+				return nil, fmt.Errorf("call to gopls.Supertypes failed: %v", err)
+			}
+		case typeHierarchySubtypes:
+			items, err = v.server.Subtypes(context.Background(), &protocol.TypeHierarchySubtypesParams{Item: item})
+			if err != nil {
+				return nil, fmt.Errorf("call to gopls.Subtypes failed: %v", err)
+			}
+		}
+		sort.SliceStable(items, func(i, j int) bool {
+			if items[i].Name != items[j].Name {
+				return items[i].Name < items[j].Name
+			}
+			return items[i].URI < items[j].URI
+		})
+		var children []*treeNode
+		for _, i := range items {
+			children = append(children, &treeNode{
+				text:   v.typeHierarchyText(i),
+				locs:   []protocol.Location{{URI: i.URI, Range: i.SelectionRange}},
+				expand: v.typeHierarchyExpand(i, dir),
+			})
+		}
+		return children, nil
+	}
+}
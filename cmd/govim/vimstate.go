@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"reflect"
 	"strings"
 	"sync"
 
@@ -23,9 +24,20 @@ type vimstate struct {
 	// or autocommand.
 	buffers map[int]*types.Buffer
 
-	// jumpStack is akin to the Vim concept of a tagstack
-	jumpStack    []protocol.Location
-	jumpStackPos int
+	// jumpStacks are the jump stacks of tab pages, keyed by the value of the
+	// jumpStackTabVar variable of a tab page
+	jumpStacks map[int]*jumpStack
+
+	// lastJumpStackID is the last key used in jumpStacks
+	lastJumpStackID int
+
+	// lastJumpStackPropID is the last ID, relative to
+	// types.JumpStackTextPropIDBase, of the text properties that mark jump
+	// stack locations
+	lastJumpStackPropID int
+
+	// jumpStackPopup is the open CommandJumpStack popup, if any
+	jumpStackPopup *jumpStackPopup
 
 	// omnifunc calls happen in pairs (see :help complete-functions). The return value
 	// from the first tells Vim where the completion starts, the return from the second
@@ -93,6 +105,71 @@ type vimstate struct {
 	// A nil value is used to indicate that there is no ongoing vimgrep.
 	// When vimgrep is done, these buffers are added to govim.
 	vimgrepPendingBufs map[int]*types.Buffer
+
+	// treeViews are the open tree view scratch buffers, e.g. created by
+	// CommandCallersOf, keyed by buffer number.
+	treeViews map[int]*treeView
+
+	// lastTreeViewID is the last ID used in the name of a tree view buffer
+	lastTreeViewID int
+
+	// lastDiffPreviewID is the last ID used in the name of a diff preview
+	// buffer
+	lastDiffPreviewID int
+
+	// cancelInlayHints cancels the ongoing inlay hint requests, if any. It is
+	// nil when there are no ongoing requests.
+	cancelInlayHints context.CancelFunc
+
+	// cancelCodeLenses cancels the ongoing code lens request of a buffer, if
+	// any, keyed by buffer number
+	cancelCodeLenses map[int]context.CancelFunc
+
+	// cancelPullDiagnostics cancels the ongoing gopls.Diagnostic request of a
+	// buffer, if any, keyed by buffer number
+	cancelPullDiagnostics map[int]context.CancelFunc
+
+	// cancelPullWorkspaceDiagnostics cancels the ongoing
+	// gopls.DiagnosticWorkspace request, if any
+	cancelPullWorkspaceDiagnostics context.CancelFunc
+
+	// diagnosticResultIDs are the result IDs of the last pulled diagnostics
+	// of documents, keyed by URI, which allow gopls to respond that the
+	// diagnostics of a document are unchanged
+	diagnosticResultIDs map[protocol.DocumentURI]string
+
+	// foldingRanges is the folding state of Go buffers, keyed by buffer number
+	foldingRanges map[int]*foldingRangesState
+
+	// messageRequests are the prompts from gopls awaiting a response, keyed by
+	// the ID of the popup showing the prompt
+	messageRequests map[int]*messageRequest
+
+	// outline is the open CommandOutline sidebar, if any
+	outline *outline
+
+	// symbolsPicker is the open CommandSymbols popup, if any
+	symbolsPicker *symbolsPicker
+
+	// locationsPicker is the open popup of several locations from which to
+	// choose the one to jump to, if any
+	locationsPicker *locationsPicker
+
+	// semanticTokens is the semantic highlighting state of buffers, keyed by
+	// buffer number
+	semanticTokens map[int]*semanticTokensState
+
+	// semanticTokensLegend is the legend used to decode semantic tokens. It is
+	// nil until gopls has advertised that it provides semantic tokens.
+	semanticTokensLegend *protocol.SemanticTokensLegend
+
+	// semanticTokensDelta indicates whether gopls supports
+	// semanticTokens/full/delta requests
+	semanticTokensDelta bool
+
+	// fileOperations are the file operations of which gopls wants to be
+	// notified. It is nil if gopls is not interested in any.
+	fileOperations *protocol.FileOperationOptions
 }
 
 // quickfixCanDiagnostics returns true if the quickfix can be populated with
@@ -132,6 +209,27 @@ func (v *vimstate) setConfig(args ...json.RawMessage) (interface{}, error) {
 		}
 	}
 
+	if !vimconfig.EqualBool(v.config.LocationListDiagnostics, preConfig.LocationListDiagnostics) {
+		if v.config.LocationListDiagnostics == nil || !*v.config.LocationListDiagnostics {
+			v.clearLocationListDiagnostics()
+		} else if err := v.updateLocationListsWithDiagnostics(true); err != nil {
+			return nil, fmt.Errorf("failed to update location lists: %v", err)
+		}
+	}
+
+	// The filtering of diagnostics happens as part of their conversion, hence
+	// they need converting again
+	if !reflect.DeepEqual(v.config.DiagnosticsMinSeverity, preConfig.DiagnosticsMinSeverity) ||
+		!reflect.DeepEqual(v.config.DiagnosticsMinSeverityBySource, preConfig.DiagnosticsMinSeverityBySource) ||
+		!reflect.DeepEqual(v.config.DiagnosticsMinSeverityByPath, preConfig.DiagnosticsMinSeverityByPath) {
+		v.diagnosticsChangedLock.Lock()
+		v.diagnosticsChanged = true
+		v.diagnosticsChangedLock.Unlock()
+		if err := v.handleDiagnosticsChanged(); err != nil {
+			return nil, fmt.Errorf("failed to update diagnostics: %v", err)
+		}
+	}
+
 	if !vimconfig.EqualBool(v.config.QuickfixSigns, preConfig.QuickfixSigns) {
 		if v.config.QuickfixSigns == nil || !*v.config.QuickfixSigns {
 			// QuickfixSigns is now not on - clear all signs
@@ -155,6 +253,15 @@ func (v *vimstate) setConfig(args ...json.RawMessage) (interface{}, error) {
 		}
 	}
 
+	if !reflect.DeepEqual(v.config.DiagnosticsVirtualText, preConfig.DiagnosticsVirtualText) ||
+		!reflect.DeepEqual(v.config.DiagnosticsVirtualTextMaxWidth, preConfig.DiagnosticsVirtualTextMaxWidth) {
+		if v.diagnosticsVirtualTextMode() == config.DiagnosticsVirtualTextOff {
+			v.removeDiagnosticsVirtualText()
+		} else {
+			v.redefineDiagnosticsVirtualText(*v.diagnostics())
+		}
+	}
+
 	if !vimconfig.EqualBool(v.config.HighlightReferences, preConfig.HighlightReferences) {
 		if v.config.HighlightReferences == nil || !*v.config.HighlightReferences {
 			// HighlightReferences is now not on - remove existing text properties
@@ -174,6 +281,36 @@ func (v *vimstate) setConfig(args ...json.RawMessage) (interface{}, error) {
 		err = v.server.DidChangeConfiguration(context.Background(), &protocol.DidChangeConfigurationParams{})
 	}
 
+	if !vimconfig.EqualBool(v.config.HighlightSemanticTokens, preConfig.HighlightSemanticTokens) {
+		if v.config.HighlightSemanticTokens == nil || !*v.config.HighlightSemanticTokens {
+			v.removeSemanticTokens()
+		} else if err == nil && v.server != nil {
+			err = v.updateAllSemanticTokens()
+		}
+	}
+
+	// Inlay hints are computed by gopls according to the config, so they can
+	// only be updated once gopls has been told about the change.
+	if err == nil && v.server != nil && !reflect.DeepEqual(v.config.InlayHints, preConfig.InlayHints) {
+		v.removeInlayHints()
+		err = v.updateInlayHints()
+	}
+
+	if !vimconfig.EqualBool(v.config.FoldingRanges, preConfig.FoldingRanges) {
+		if v.config.FoldingRanges == nil || !*v.config.FoldingRanges {
+			v.removeFoldingRanges()
+		} else if err == nil && v.server != nil {
+			err = v.updateAllFoldingRanges()
+		}
+	}
+
+	// Likewise for code lenses, which also depend on the enabled sources
+	if err == nil && v.server != nil && (!vimconfig.EqualBool(v.config.ShowCodeLenses, preConfig.ShowCodeLenses) ||
+		!reflect.DeepEqual(v.config.CodeLenses, preConfig.CodeLenses)) {
+		v.removeCodeLenses()
+		err = v.updateAllCodeLenses()
+	}
+
 	return nil, err
 }
 
@@ -201,6 +338,9 @@ func (v *vimstate) popupSelection(args ...json.RawMessage) (interface{}, error)
 	}
 
 	fix := fixes[selection-1]
+	if err := fix.resolve(v); err != nil {
+		return nil, err
+	}
 
 	// Edits should be applied before any Command according to LSP 3.16.
 	if len(fix.edit.DocumentChanges) > 0 {
@@ -210,41 +350,80 @@ func (v *vimstate) popupSelection(args ...json.RawMessage) (interface{}, error)
 	}
 
 	if fix.command != nil {
-		editsCh := make(chan applyEditCall)
-		v.govimplugin.applyEditsLock.Lock()
-		v.govimplugin.applyEditsCh = editsCh
-		v.govimplugin.applyEditsLock.Unlock()
-		done := make(chan struct{})
-
-		var ecErr error
-		v.tomb.Go(func() error {
-			_, ecErr = v.server.ExecuteCommand(context.Background(),
-				&protocol.ExecuteCommandParams{
-					Command:   fix.command.Command,
-					Arguments: fix.command.Arguments,
-				})
-
-			v.govimplugin.applyEditsLock.Lock()
-			v.govimplugin.applyEditsCh = nil
-			v.govimplugin.applyEditsLock.Unlock()
-			close(done)
-			return nil
+		if fix.lens {
+			return nil, v.runCodeLens(fix.command)
+		}
+		return nil, v.executeCommand(fix.command, nil)
+	}
+	return nil, nil
+}
+
+// executeCommand executes cmd via gopls, applying any edits gopls requests
+// whilst doing so. If token is not nil, gopls reports the progress of the
+// command using token.
+func (v *vimstate) executeCommand(cmd *protocol.Command, token protocol.ProgressToken) error {
+	err := v.callBlocking(func() error {
+		_, err := v.server.ExecuteCommand(context.Background(), &protocol.ExecuteCommandParams{
+			Command:   cmd.Command,
+			Arguments: cmd.Arguments,
+			WorkDoneProgressParams: protocol.WorkDoneProgressParams{
+				WorkDoneToken: token,
+			},
 		})
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("executeCommand failed: %v", err)
+	}
+	return nil
+}
+
+// callBlocking calls f, a blocking call to gopls, handling on the Vim thread
+// the calls gopls makes back to govim until f returns.
+func (v *vimstate) callBlocking(f func() error) error {
+	// A blocking call to gopls, e.g. ExecuteCommand, may only be released once
+	// gopls' calls back to govim, e.g. ApplyEdit, have been handled. Since f
+	// is called on the Vim thread (and given the single threaded nature of
+	// vim), we are effectively blocking ApplyEdit from modifying buffers.
+	//
+	// To prevent a deadlock, we create channels that ApplyEdit and
+	// ShowMessageRequest can use to pass their calls to this thread. And then
+	// call f in a separate goroutine so that this thread can go on handling
+	// them until f returns. When it does, we implicitly know that the calls
+	// have been handled.
+	editsCh := make(chan applyEditCall)
+	promptsCh := make(chan *messageRequest)
+	v.govimplugin.applyEditsLock.Lock()
+	v.govimplugin.applyEditsCh = editsCh
+	v.govimplugin.messageRequestsCh = promptsCh
+	v.govimplugin.applyEditsLock.Unlock()
+	done := make(chan struct{})
+
+	var callErr error
+	v.tomb.Go(func() error {
+		callErr = f()
 
-		for {
-			select {
-			case <-done:
-				if ecErr != nil {
-					return nil, fmt.Errorf("executeCommand failed: %v", ecErr)
-				}
-				return nil, nil
-			case c := <-editsCh:
-				res, err := v.applyWorkspaceEdit(c.params)
-				c.responseCh <- applyEditResponse{res, err}
+		v.govimplugin.applyEditsLock.Lock()
+		v.govimplugin.applyEditsCh = nil
+		v.govimplugin.messageRequestsCh = nil
+		v.govimplugin.applyEditsLock.Unlock()
+		close(done)
+		return nil
+	})
+
+	for {
+		select {
+		case <-done:
+			return callErr
+		case c := <-editsCh:
+			res, err := v.applyWorkspaceEdit(c.params)
+			c.responseCh <- applyEditResponse{res, err}
+		case req := <-promptsCh:
+			if err := v.promptMessageRequest(req); err != nil {
+				v.Logf("failed to prompt for %q: %v", req.params.Message, err)
 			}
 		}
 	}
-	return nil, nil
 }
 
 func (v *vimstate) progressClosed(args ...json.RawMessage) (interface{}, error) {
@@ -284,6 +463,11 @@ func (v *vimstate) setUserBusy(args ...json.RawMessage) (interface{}, error) {
 	if err := v.handleDiagnosticsChanged(); err != nil {
 		return nil, err
 	}
+	v.setDiagnosticsVirtualTextCursor(pos)
+	if err := v.updateInlayHints(); err != nil {
+		return nil, err
+	}
+	v.updateOutlineCursor(pos)
 	return nil, nil
 }
 
@@ -304,12 +488,30 @@ func (v *vimstate) applyWorkspaceEdit(params *protocol.ApplyWorkspaceEditParams)
 	res := &protocol.ApplyWorkspaceEditResult{Applied: true}
 
 	edits := make(map[*types.Buffer][]protocol.TextEdit)
+	// applyEdits applies the text edits collected so far
+	applyEdits := func() error {
+		for b, e := range edits {
+			if err := v.applyProtocolTextEdits(b, e); err != nil {
+				return err
+			}
+		}
+		edits = make(map[*types.Buffer][]protocol.TextEdit)
+		return nil
+	}
 	for _, dc := range params.Edit.DocumentChanges {
-		// TODO: protocol.DocumentChanges is a union that (currently) must have
-		// either TextDocumentEdit or RenameFile set. We should add support for
-		// renaming files as well.
 		if dc.TextDocumentEdit == nil {
-			return nil, fmt.Errorf("got unsupported workspace edit from gopls (e.g. file rename)")
+			// Files are created, renamed or deleted after the text edits
+			// that precede them
+			err := applyEdits()
+			if err == nil {
+				err = v.applyResourceOperation(dc)
+			}
+			if err != nil {
+				res.FailureReason = err.Error()
+				res.Applied = false
+				return res, nil
+			}
+			continue
 		}
 		textDoc := dc.TextDocumentEdit.TextDocument
 
@@ -337,12 +539,9 @@ func (v *vimstate) applyWorkspaceEdit(params *protocol.ApplyWorkspaceEditParams)
 		edits[buf] = append(edits[buf], protocol.AsTextEdits(dc.TextDocumentEdit.Edits)...)
 	}
 
-	for b, e := range edits {
-		if err := v.applyProtocolTextEdits(b, e); err != nil {
-			res.FailureReason = err.Error()
-			res.Applied = false
-			return res, nil
-		}
+	if err := applyEdits(); err != nil {
+		res.FailureReason = err.Error()
+		res.Applied = false
 	}
 	return res, nil
 }
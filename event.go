@@ -107,5 +107,6 @@ const (
 	EventSessionLoadPost                   // SessionLoadPost
 	EventMenuPopup                         // MenuPopup
 	EventCompleteDone                      // CompleteDone
+	EventWinScrolled                       // WinScrolled
 	EventUser                              // User
 )
@@ -4,6 +4,9 @@ setlocal balloonexpr=GOVIM_internal_BalloonExpr()
 " Completion
 setlocal omnifunc=GOVIM_internal_Complete
 
+" Folding, which is enabled by setting foldmethod=expr
+setlocal foldexpr=GOVIMFoldExpr()
+
 " go-to-def
 nnoremap <buffer> <silent> gd :GOVIMGoToDef<cr>
 nnoremap <buffer> <silent> <C-]> :GOVIMGoToDef<cr>
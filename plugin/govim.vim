@@ -212,6 +212,14 @@ function GOVIMPluginStatus(...)
   return s:govim_status
 endfunction
 
+" GOVIMFoldExpr is the foldexpr of Go buffers. The fold level of each line is
+" computed by govim from the folding ranges reported by gopls, when the
+" FoldingRanges config is enabled, such that Vim's per-line queries are
+" answered without calling govim.
+function GOVIMFoldExpr()
+  return get(get(b:, "govim_fold_levels", []), v:lnum-1, "=")
+endfunction
+
 function s:userBusy(busy)
   if s:userBusy != a:busy
     let s:userBusy = a:busy
@@ -593,6 +601,52 @@ function GOVIM_internal_SuggestedFixesFilter(id, key)
     return popup_filter_menu(a:id, a:key)
 endfunc
 
+" GOVIM_internal_SymbolsFilter edits the query of the GOVIMSymbols popup,
+" which is held in a window variable of the popup, and handles moving the
+" selection. All keys are consumed so that the popup behaves like a prompt.
+function GOVIM_internal_SymbolsFilter(id, key)
+    let l:query = getwinvar(a:id, "govim_symbols_query", "")
+    if a:key == "\<CR>"
+        call popup_close(a:id, line(".", a:id))
+    elseif a:key == "\<Esc>" || a:key == "\<C-c>"
+        call popup_close(a:id, -1)
+    elseif a:key == "\<C-n>" || a:key == "\<Down>"
+        call win_execute(a:id, "normal! j")
+    elseif a:key == "\<C-p>" || a:key == "\<Up>"
+        call win_execute(a:id, "normal! k")
+    elseif a:key == "\<BS>" || a:key == "\<C-h>"
+        if l:query != ""
+            let l:query = strcharpart(l:query, 0, strchars(l:query)-1)
+            call setwinvar(a:id, "govim_symbols_query", l:query)
+            call GOVIM_internal_SymbolsQuery(a:id, l:query)
+        endif
+    elseif a:key =~ '^\p$'
+        let l:query .= a:key
+        call setwinvar(a:id, "govim_symbols_query", l:query)
+        call GOVIM_internal_SymbolsQuery(a:id, l:query)
+    endif
+    return 1
+endfunction
+
+" GOVIM_internal_MessageRequestFilter handles the keys of the popup that shows
+" a prompt from gopls. The actions of the prompt follow its message, starting
+" at the line held in a window variable of the popup, and only those lines
+" can be selected. As with popup_filter_menu(), all keys are consumed.
+function GOVIM_internal_MessageRequestFilter(id, key)
+    let l:first = getwinvar(a:id, "govim_message_request_first", 1)
+    let l:line = line(".", a:id)
+    if a:key == "\<CR>" || a:key == " "
+        call popup_close(a:id, l:line - l:first + 1)
+    elseif a:key == "\<Esc>" || a:key == "\<C-c>" || a:key == "x"
+        call popup_close(a:id, -1)
+    elseif a:key == "j" || a:key == "\<C-n>" || a:key == "\<Down>"
+        call win_execute(a:id, "normal! j")
+    elseif (a:key == "k" || a:key == "\<C-p>" || a:key == "\<Up>") && l:line > l:first
+        call win_execute(a:id, "normal! k")
+    endif
+    return 1
+endfunction
+
 " In case we are running in test mode
 if $GOVIM_DISABLE_USER_BUSY == "true"
   function GOVIM_test_SetUserBusy(busy)